@@ -0,0 +1,279 @@
+package simpleforce
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the formats of the date, datetime and time fields returned by the API.
+var timeLayouts = []string{
+	"2006-01-02T15:04:05.000-0700",
+	time.RFC3339Nano,
+	"2006-01-02",
+	"15:04:05.000Z",
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	sobjectType = reflect.TypeOf(SObject{})
+)
+
+// Decode stores the fields of the SObject in the struct pointed to by v. A struct field is filled from the record
+// field named by its "sf" tag, or by its own name if it has no tag, compared case-insensitively; fields tagged "-" are
+// skipped. Fields absent from the record are left untouched:
+//
+//	type Contact struct {
+//		ID          string     `sf:"Id"`
+//		Name        string
+//		AccountName string     `sf:"Account.Name"`  // parent relationship field.
+//		Owner       *User      `sf:"Owner"`         // parent record, nil if null.
+//		Cases       []Case     `sf:"Cases"`         // child subquery result.
+//		Birthdate   *time.Time `sf:"Birthdate"`     // date or datetime, nil if null.
+//	}
+//
+// Null values set pointers, slices, maps and interfaces to nil and other types to their zero value. Date, datetime and
+// time strings are parsed into time.Time, nested records into structs, maps or SObject, and subquery results into
+// slices. Salesforce returns the child records of a subquery in batches; Decode returns an error rather than drop
+// records if a subquery result is not done, in which case the child records must be queried on their own.
+func (obj *SObject) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Decode requires a non-nil pointer to a struct, got %T", v)
+	}
+	return decodeRecord(*obj, rv.Elem(), "")
+}
+
+// QueryInto runs the SOQL query q and stores all its records, from every page of the result, in the slice pointed to
+// by v. The elements of the slice are structs or pointers to structs, decoded like with SObject.Decode:
+//
+//	var contacts []Contact
+//	err := client.QueryInto("SELECT Id, Name, Account.Name FROM Contact", &contacts)
+func (client *Client) QueryInto(q string, v interface{}) error {
+	return client.QueryIntoContext(context.Background(), q, v)
+}
+
+// QueryIntoContext is like QueryInto, the requests are bound to ctx.
+func (client *Client) QueryIntoContext(ctx context.Context, q string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("QueryInto requires a non-nil pointer to a slice, got %T", v)
+	}
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("QueryInto requires a slice of structs, got %T", v)
+	}
+
+	out := reflect.MakeSlice(slice.Type(), 0, 0)
+	it := client.QueryIteratorContext(ctx, q)
+	for it.Next() {
+		elem := reflect.New(structType)
+		if err := decodeRecord(*it.Record(), elem.Elem(), ""); err != nil {
+			return err
+		}
+		if elemType.Kind() == reflect.Ptr {
+			out = reflect.Append(out, elem)
+		} else {
+			out = reflect.Append(out, elem.Elem())
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	slice.Set(out)
+	return nil
+}
+
+// decodeRecord stores the fields of a record in the struct v. prefix is the path of the record, for error messages.
+func decodeRecord(fields map[string]interface{}, v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("sf")
+		if tag == "-" {
+			continue
+		}
+		if tag == "" && field.Anonymous && field.Type.Kind() == reflect.Struct && field.Type != timeType {
+			// The fields of embedded structs are promoted.
+			if err := decodeRecord(fields, v.Field(i), prefix); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			// Unexported.
+			continue
+		}
+
+		name := tag
+		if name == "" {
+			name = field.Name
+		}
+		value, ok := fieldPath(fields, name)
+		if !ok {
+			continue
+		}
+		if err := decodeValue(value, v.Field(i), prefix+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldPath returns the value of a field or dotted relationship path such as Account.Owner.Name, compared
+// case-insensitively, and whether it is present. The value is nil if a parent record on the path is null.
+func fieldPath(fields map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = fields
+	for _, name := range strings.Split(path, ".") {
+		if value == nil {
+			return nil, true
+		}
+		record, ok := asRecord(value)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = fieldValue(record, name); !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// fieldValue finds a field by name, preferring an exact match.
+func fieldValue(fields map[string]interface{}, name string) (interface{}, bool) {
+	if value, ok := fields[name]; ok {
+		return value, true
+	}
+	for key, value := range fields {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// asRecord returns value as the fields of a record, if it is one.
+func asRecord(value interface{}) (map[string]interface{}, bool) {
+	switch record := value.(type) {
+	case map[string]interface{}:
+		return record, true
+	case SObject:
+		return record, true
+	case *SObject:
+		if record != nil {
+			return *record, true
+		}
+	}
+	return nil, false
+}
+
+// decodeValue stores a field value decoded from JSON in v. path is the path of the field, for error messages.
+func decodeValue(value interface{}, v reflect.Value, path string) error {
+	if value == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch {
+	case v.Kind() == reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := decodeValue(value, elem.Elem(), path); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case v.Type() == timeType:
+		s, ok := value.(string)
+		if !ok {
+			return decodeError(value, v, path)
+		}
+		for _, layout := range timeLayouts {
+			if parsed, err := time.Parse(layout, s); err == nil {
+				v.Set(reflect.ValueOf(parsed))
+				return nil
+			}
+		}
+		return fmt.Errorf("cannot parse %q of field %s as a time", s, path)
+	case v.Type() == sobjectType:
+		record, ok := asRecord(value)
+		if !ok {
+			return decodeError(value, v, path)
+		}
+		v.Set(reflect.ValueOf(SObject(record)))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if reflect.TypeOf(value).AssignableTo(v.Type()) {
+			v.Set(reflect.ValueOf(value))
+			return nil
+		}
+	case reflect.String:
+		if s, ok := value.(string); ok {
+			v.SetString(s)
+			return nil
+		}
+	case reflect.Bool:
+		if b, ok := value.(bool); ok {
+			v.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f, ok := value.(float64); ok && f == float64(int64(f)) && !v.OverflowInt(int64(f)) {
+			v.SetInt(int64(f))
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if f, ok := value.(float64); ok && f >= 0 && f == float64(uint64(f)) && !v.OverflowUint(uint64(f)) {
+			v.SetUint(uint64(f))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, ok := value.(float64); ok {
+			v.SetFloat(f)
+			return nil
+		}
+	case reflect.Struct:
+		if record, ok := asRecord(value); ok {
+			return decodeRecord(record, v, path+".")
+		}
+	case reflect.Map:
+		if record, ok := asRecord(value); ok && reflect.TypeOf(record).AssignableTo(v.Type()) {
+			v.Set(reflect.ValueOf(record))
+			return nil
+		}
+	case reflect.Slice:
+		// Subquery results hold their records like a query result.
+		if result, ok := asRecord(value); ok {
+			if done, ok := result["done"].(bool); ok && !done {
+				return fmt.Errorf("subquery %s returned more records than its first batch, query them separately", path)
+			}
+			if records, ok := result["records"]; ok {
+				value = records
+			}
+		}
+		if values, ok := value.([]interface{}); ok {
+			out := reflect.MakeSlice(v.Type(), len(values), len(values))
+			for i, elem := range values {
+				if err := decodeValue(elem, out.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			v.Set(out)
+			return nil
+		}
+	}
+	return decodeError(value, v, path)
+}
+
+func decodeError(value interface{}, v reflect.Value, path string) error {
+	return fmt.Errorf("cannot decode %T into field %s of type %s", value, path, v.Type())
+}
@@ -0,0 +1,169 @@
+package simpleforce
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/veloceapps/simpleforce/simpleforcetest"
+)
+
+type decodeUser struct {
+	Name string
+}
+
+type decodeContact struct {
+	ID    string `sf:"Id"`
+	Email *string
+}
+
+type decodeAudit struct {
+	CreatedDate time.Time
+}
+
+type decodeAccount struct {
+	decodeAudit
+	ID            string          `sf:"Id"`
+	Name          string          `sf:"name"`
+	Employees     int             `sf:"NumberOfEmployees"`
+	Revenue       *float64        `sf:"AnnualRevenue"`
+	Active        bool            `sf:"Active__c"`
+	Founded       *time.Time      `sf:"Founded__c"`
+	Closed        *time.Time      `sf:"Closed__c"`
+	OwnerName     string          `sf:"Owner.Name"`
+	ManagerName   *string         `sf:"Owner.Manager.Name"`
+	Owner         *decodeUser     `sf:"Owner"`
+	Parent        *decodeUser     `sf:"Parent"`
+	Contacts      []decodeContact `sf:"Contacts"`
+	ContactsRaw   []SObject       `sf:"Contacts"`
+	Extra         interface{}     `sf:"Extra__c"`
+	Ignored       string          `sf:"-"`
+	Missing       string
+	notExported   string
+	ParentRecord  SObject  `sf:"Owner"`
+	PreviousNames []string `sf:"Previous_Names__c"`
+}
+
+func TestSObject_Decode(t *testing.T) {
+	var obj SObject
+	err := json.Unmarshal([]byte(`{
+		"attributes": {"type": "Account", "url": "/services/data/v53.0/sobjects/Account/001"},
+		"Id": "001",
+		"Name": "Acme",
+		"NumberOfEmployees": 42,
+		"AnnualRevenue": null,
+		"Active__c": true,
+		"Founded__c": "1999-12-31",
+		"Closed__c": null,
+		"CreatedDate": "2024-01-02T03:04:05.000+0000",
+		"Owner": {"attributes": {"type": "User"}, "Name": "Jane", "Manager": null},
+		"Parent": null,
+		"Contacts": {"totalSize": 2, "done": true, "records": [
+			{"attributes": {"type": "Contact"}, "Id": "003A", "Email": "a@example.com"},
+			{"attributes": {"type": "Contact"}, "Id": "003B", "Email": null}
+		]},
+		"Extra__c": 1.5,
+		"Ignored": "x",
+		"Previous_Names__c": ["Acme Corp"]
+	}`), &obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account := decodeAccount{Missing: "kept", Ignored: "kept"}
+	if err = obj.Decode(&account); err != nil {
+		t.Fatal(err)
+	}
+	if account.ID != "001" || account.Name != "Acme" || account.Employees != 42 || account.Revenue != nil ||
+		!account.Active || account.Closed != nil || account.Extra != 1.5 || account.Ignored != "kept" ||
+		account.Missing != "kept" || len(account.PreviousNames) != 1 {
+		t.Errorf("unexpected fields %+v", account)
+	}
+	if account.Founded == nil || !account.Founded.Equal(time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC)) ||
+		!account.CreatedDate.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("unexpected times %v %v", account.Founded, account.CreatedDate)
+	}
+	if account.OwnerName != "Jane" || account.ManagerName != nil || account.Owner == nil ||
+		account.Owner.Name != "Jane" || account.Parent != nil || account.ParentRecord.StringField("Name") != "Jane" {
+		t.Errorf("unexpected parents %+v", account)
+	}
+	if len(account.Contacts) != 2 || account.Contacts[0].ID != "003A" || *account.Contacts[0].Email != "a@example.com" ||
+		account.Contacts[1].Email != nil || len(account.ContactsRaw) != 2 || account.ContactsRaw[1].ID() != "003B" {
+		t.Errorf("unexpected children %+v", account.Contacts)
+	}
+
+	// Negative: mismatched types and invalid targets are rejected.
+	var wrong struct {
+		Name int
+	}
+	if err = obj.Decode(&wrong); err == nil {
+		t.Fail()
+	}
+	var badTime struct {
+		Name time.Time
+	}
+	if err = obj.Decode(&badTime); err == nil {
+		t.Fail()
+	}
+	if err = obj.Decode(account); err == nil {
+		t.Fail()
+	}
+
+	// Negative: child records beyond the first batch are not dropped silently.
+	var partial SObject
+	if err = json.Unmarshal([]byte(`{"Contacts": {"totalSize": 300, "done": false,
+		"nextRecordsUrl": "/services/data/v62.0/query/01gA-200", "records": [{"Id": "003A"}]}}`), &partial); err != nil {
+		t.Fatal(err)
+	}
+	var children struct {
+		Contacts []decodeContact
+	}
+	if err = partial.Decode(&children); err == nil || children.Contacts != nil {
+		t.Errorf("unexpected %+v %v", children, err)
+	}
+}
+
+func TestClient_QueryInto(t *testing.T) {
+	server := simpleforcetest.NewServer()
+	defer server.Close()
+	server.SetPageSize(2)
+	for _, name := range []string{"Alpha", "Beta", "Gamma"} {
+		server.Insert("Account", map[string]interface{}{
+			"Name":        name,
+			"CreatedDate": "2024-01-02T03:04:05.000+0000",
+			"Owner":       map[string]interface{}{"Name": "Jane"},
+		})
+	}
+
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	client.SetSidLoc(server.NewSession(), server.URL)
+
+	// The records of every page are decoded.
+	var accounts []decodeAccount
+	err := client.QueryInto("SELECT Id, Name, CreatedDate, Owner.Name FROM Account ORDER BY Name", &accounts)
+	if err != nil || len(accounts) != 3 {
+		t.Fatalf("unexpected %d accounts %v", len(accounts), err)
+	}
+	if accounts[2].Name != "Gamma" || accounts[2].OwnerName != "Jane" || accounts[2].ID == "" ||
+		accounts[2].CreatedDate.IsZero() {
+		t.Errorf("unexpected account %+v", accounts[2])
+	}
+
+	var pointers []*decodeAccount
+	if err = client.QueryInto("SELECT Id, Name FROM Account WHERE Name = 'beta'", &pointers); err != nil ||
+		len(pointers) != 1 || pointers[0].Name != "Beta" {
+		t.Errorf("unexpected %v %v", pointers, err)
+	}
+
+	// Negative: invalid targets and failed queries are rejected.
+	if err = client.QueryInto("SELECT Id FROM Account", accounts); err == nil {
+		t.Fail()
+	}
+	var names []string
+	if err = client.QueryInto("SELECT Name FROM Account", &names); err == nil {
+		t.Fail()
+	}
+	if err = client.QueryInto("SELECT FROM Account", &accounts); err == nil || len(accounts) != 3 {
+		t.Errorf("unexpected %v", err)
+	}
+}
@@ -1,11 +1,14 @@
 package simpleforce
 
 import (
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"net/http"
+	"strings"
+
 	"github.com/pkg/errors"
-	"log"
 )
 
 var (
@@ -14,39 +17,165 @@ var (
 
 	// ErrAuthentication is returned when authentication failed.
 	ErrAuthentication = errors.New("authentication failure")
+
+	// ErrInvalidSObject is returned when an SObject has no type or was not created by a Client.
+	ErrInvalidSObject = errors.New("sobject has no type or client")
+
+	// ErrMissingID is returned when an operation requires the ID of an SObject and none is known.
+	ErrMissingID = errors.New("sobject id not found")
+
+	// ErrToolingUnsupported is returned when an operation is not available in the Tooling API.
+	ErrToolingUnsupported = errors.New("operation not supported by the tooling api")
+)
+
+// ErrorCode is an error code reported by the Salesforce API. Use errors.Is(err, code) to check if an *APIError
+// carries the code.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/errorcodes.htm
+type ErrorCode string
+
+func (code ErrorCode) Error() string {
+	return string(code)
+}
+
+// Error codes commonly handled by callers.
+const (
+	ErrInvalidSessionID               ErrorCode = "INVALID_SESSION_ID"
+	ErrInvalidLogin                   ErrorCode = "INVALID_LOGIN"
+	ErrEntityIsDeleted                ErrorCode = "ENTITY_IS_DELETED"
+	ErrDuplicateValue                 ErrorCode = "DUPLICATE_VALUE"
+	ErrDuplicatesDetected             ErrorCode = "DUPLICATES_DETECTED"
+	ErrNotFound                       ErrorCode = "NOT_FOUND"
+	ErrInvalidField                   ErrorCode = "INVALID_FIELD"
+	ErrInvalidType                    ErrorCode = "INVALID_TYPE"
+	ErrMalformedQuery                 ErrorCode = "MALFORMED_QUERY"
+	ErrRequiredFieldMissing           ErrorCode = "REQUIRED_FIELD_MISSING"
+	ErrFieldCustomValidationException ErrorCode = "FIELD_CUSTOM_VALIDATION_EXCEPTION"
+	ErrInsufficientAccess             ErrorCode = "INSUFFICIENT_ACCESS_OR_READONLY"
+	ErrRequestLimitExceeded           ErrorCode = "REQUEST_LIMIT_EXCEEDED"
+	ErrUnableToLockRow                ErrorCode = "UNABLE_TO_LOCK_ROW"
+	ErrServerUnavailable              ErrorCode = "SERVER_UNAVAILABLE"
 )
 
-type jsonError []struct {
-	Message   string `json:"message"`
-	ErrorCode string `json:"errorCode"`
+// ErrorEntry is a single error reported by Salesforce. The REST API reports errors with errorCode, while the per-record
+// results of create and composite requests use statusCode; both are decoded into ErrorCode.
+type ErrorEntry struct {
+	ErrorCode string   `json:"errorCode"`
+	Message   string   `json:"message"`
+	Fields    []string `json:"fields,omitempty"`
+}
+
+// UnmarshalJSON accepts both the errorCode and the statusCode form of an error.
+func (entry *ErrorEntry) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		ErrorCode  string   `json:"errorCode"`
+		StatusCode string   `json:"statusCode"`
+		Message    string   `json:"message"`
+		Fields     []string `json:"fields"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	entry.ErrorCode = firstNonEmpty(raw.ErrorCode, raw.StatusCode)
+	entry.Message = raw.Message
+	entry.Fields = raw.Fields
+	return nil
+}
+
+// APIError is returned when Salesforce rejects a request. It carries the HTTP status, every error reported in the
+// response, and the raw response body.
+type APIError struct {
+	StatusCode int
+	Errors     []ErrorEntry
+	Body       []byte
+}
+
+func (e *APIError) Error() string {
+	if len(e.Errors) == 0 {
+		body := strings.TrimSpace(string(e.Body))
+		if len(body) > 200 {
+			body = body[:200] + "..."
+		}
+		return fmt.Sprintf(logPrefix+" Error. http code: %v Response: %v", e.StatusCode, body)
+	}
+
+	message := fmt.Sprintf(logPrefix+" Error. http code: %v Error Message:  %v Error Code: %v", e.StatusCode,
+		e.Errors[0].Message, e.Errors[0].ErrorCode)
+	for _, entry := range e.Errors[1:] {
+		message += fmt.Sprintf("; Error Message: %v Error Code: %v", entry.Message, entry.ErrorCode)
+	}
+	return message
+}
+
+// Code returns the error code of the first error reported, or an empty string if the response had none.
+func (e *APIError) Code() string {
+	if len(e.Errors) == 0 {
+		return ""
+	}
+	return e.Errors[0].ErrorCode
+}
+
+// HasCode returns if any of the errors reported carries code.
+func (e *APIError) HasCode(code ErrorCode) bool {
+	for _, entry := range e.Errors {
+		if entry.ErrorCode == string(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// Is allows errors.Is to match an APIError against the ErrorCode values, ErrAuthentication for rejected sessions
+// and logins, and ErrFailure for responses which could not be decoded.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrFailure:
+		return len(e.Errors) == 0
+	case ErrAuthentication:
+		return e.StatusCode == http.StatusUnauthorized || e.HasCode(ErrInvalidSessionID) || e.HasCode(ErrInvalidLogin)
+	}
+	if code, ok := target.(ErrorCode); ok {
+		return e.HasCode(code)
+	}
+	return false
 }
 
 type xmlError struct {
-	Message string `xml:"Body>Fault>faultstring"`
+	Message   string `xml:"Body>Fault>faultstring"`
 	ErrorCode string `xml:"Body>Fault>faultcode"`
 }
 
-//Need to get information out of this package.
+// ParseSalesforceError decodes the error response of a failed request. The returned error is always an *APIError;
+// responses which are neither JSON nor XML are kept in its Body.
 func ParseSalesforceError(statusCode int, responseBody []byte) (err error) {
-	jsonError := jsonError{}
-	xmlError := xmlError{}
-	err = json.Unmarshal(responseBody, &jsonError)
-	if err != nil {
-		//Unable to parse json. Try xml
-		err = xml.Unmarshal(responseBody, &xmlError)
-		if err != nil {
-			//Unable to parse json or XML
-			log.Println("ERROR UNMARSHALLING: ", err)
-			return ErrFailure
+	return parseAPIError(statusCode, responseBody)
+}
+
+// parseAPIError decodes a JSON error array, a single JSON error, or a SOAP fault.
+func parseAPIError(statusCode int, responseBody []byte) *APIError {
+	apiError := &APIError{StatusCode: statusCode, Body: responseBody}
+	trimmed := bytes.TrimSpace(responseBody)
+
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		var entries []ErrorEntry
+		if json.Unmarshal(trimmed, &entries) == nil {
+			apiError.Errors = entries
+		}
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var entry ErrorEntry
+		if json.Unmarshal(trimmed, &entry) == nil && entry.ErrorCode != "" {
+			apiError.Errors = []ErrorEntry{entry}
+		}
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		var fault xmlError
+		if xml.Unmarshal(trimmed, &fault) == nil && (fault.ErrorCode != "" || fault.Message != "") {
+			// SOAP fault codes are namespace qualified, e.g. sf:INVALID_LOGIN.
+			code := fault.ErrorCode
+			if i := strings.LastIndex(code, ":"); i != -1 {
+				code = code[i+1:]
+			}
+			apiError.Errors = []ErrorEntry{{ErrorCode: code, Message: fault.Message}}
 		}
-		//successfully parsed XML:
-		message := fmt.Sprintf(logPrefix+" Error. http code: %v Error Message:  %v Error Code: %v", statusCode, xmlError.Message, xmlError.ErrorCode)
-		err = errors.New(message)
-		return err
-	} else {
-		//Successfully parsed json error:
-		message := fmt.Sprintf(logPrefix+" Error. http code: %v Error Message:  %v Error Code: %v", statusCode, jsonError[0].Message, jsonError[0].ErrorCode)
-		err = errors.New(message)
-		return err
 	}
+	return apiError
 }
@@ -0,0 +1,65 @@
+package simpleforce
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseSalesforceError(t *testing.T) {
+	// All entries of a JSON error array are kept.
+	body := []byte(`[{"message":"Required fields are missing: [Name]","errorCode":"REQUIRED_FIELD_MISSING","fields":["Name"]},{"message":"duplicate value found","errorCode":"DUPLICATE_VALUE","fields":[]}]`)
+	err := ParseSalesforceError(http.StatusBadRequest, body)
+	var apiError *APIError
+	if !errors.As(err, &apiError) {
+		t.Fatalf("unexpected error type %T", err)
+	}
+	if apiError.StatusCode != http.StatusBadRequest || len(apiError.Errors) != 2 || string(apiError.Body) != string(body) {
+		t.Fatalf("unexpected error %+v", apiError)
+	}
+	if apiError.Code() != "REQUIRED_FIELD_MISSING" || len(apiError.Errors[0].Fields) != 1 || apiError.Errors[0].Fields[0] != "Name" {
+		t.Fail()
+	}
+	if !errors.Is(err, ErrRequiredFieldMissing) || !errors.Is(err, ErrDuplicateValue) || errors.Is(err, ErrEntityIsDeleted) {
+		t.Fail()
+	}
+	if errors.Is(err, ErrFailure) || errors.Is(err, ErrAuthentication) {
+		t.Fail()
+	}
+	if !strings.HasPrefix(err.Error(), "[simpleforce] Error. http code: 400 Error Message:  Required fields are missing: [Name] Error Code: REQUIRED_FIELD_MISSING") {
+		t.Fatalf("unexpected message %q", err.Error())
+	}
+
+	// Per-record results use statusCode.
+	err = ParseSalesforceError(http.StatusBadRequest, []byte(`{"message":"entity is deleted","statusCode":"ENTITY_IS_DELETED","fields":[]}`))
+	if !errors.Is(err, ErrEntityIsDeleted) {
+		t.Fail()
+	}
+
+	// Errors wrapped by callers are still matched.
+	err = ParseSalesforceError(http.StatusUnauthorized, []byte(`[{"message":"Session expired or invalid","errorCode":"INVALID_SESSION_ID"}]`))
+	wrapped := fmt.Errorf("update failed: %w", err)
+	if !errors.Is(wrapped, ErrInvalidSessionID) || !errors.Is(wrapped, ErrAuthentication) {
+		t.Fail()
+	}
+
+	// SOAP faults drop the namespace of the code.
+	err = ParseSalesforceError(http.StatusInternalServerError, []byte(`<?xml version="1.0" encoding="UTF-8"?><soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:sf="urn:fault.partner.soap.sforce.com"><soapenv:Body><soapenv:Fault><faultcode>sf:INVALID_LOGIN</faultcode><faultstring>INVALID_LOGIN: Invalid username, password, security token; or user locked out.</faultstring></soapenv:Fault></soapenv:Body></soapenv:Envelope>`))
+	if !errors.As(err, &apiError) || apiError.Code() != "INVALID_LOGIN" || !errors.Is(err, ErrInvalidLogin) || !errors.Is(err, ErrAuthentication) {
+		t.Fail()
+	}
+
+	// Anything else keeps the status and body.
+	err = ParseSalesforceError(http.StatusServiceUnavailable, []byte("<html><body>Service Unavailable</body></html>"))
+	if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusServiceUnavailable || len(apiError.Errors) != 0 {
+		t.Fail()
+	}
+	if !errors.Is(err, ErrFailure) || !strings.Contains(err.Error(), "Service Unavailable") {
+		t.Fail()
+	}
+	if err = ParseSalesforceError(http.StatusBadGateway, nil); !errors.Is(err, ErrFailure) {
+		t.Fail()
+	}
+}
@@ -2,20 +2,19 @@ package simpleforce
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"html"
 	"io"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
 	"os"
-	"strconv"
 	"strings"
-
-	"github.com/pkg/errors"
+	"sync"
+	"time"
 )
 
 const (
@@ -27,21 +26,53 @@ const (
 	logPrefix = "[simpleforce]"
 )
 
-// Client is the main instance to access salesforce.
+// Client is the main instance to access salesforce. A Client must be created with NewClient or NewClientFromAuthURL,
+// the zero value is not usable. A Client is safe for concurrent use by multiple goroutines, the session may be renewed
+// or replaced while requests are in flight. The Set... methods configuring the client must be called before it is
+// shared.
 type Client struct {
-	sessionID string
-	user      struct {
+	*clientState
+	useToolingAPI bool // set on the views returned by Tooling.
+}
+
+// clientState is the state shared by a Client and its tooling views.
+type clientState struct {
+	mu        sync.RWMutex // guards session.
+	session   session
+	refreshMu sync.Mutex // serializes session refreshes.
+
+	clientID     string
+	clientSecret string
+	tokenStore   TokenStore
+	retryPolicy  *RetryPolicy
+	apiVersion   string
+	baseURL      string
+	httpClient   *http.Client
+	logger       Logger
+	middleware   []Middleware
+	usage        *apiUsageTracker
+	limiter      *rateLimiter
+	inFlight     chan struct{} // slots of SetMaxConcurrentRequests.
+	headers      http.Header   // headers of SetHeader.
+}
+
+// session is the login state of a Client.
+type session struct {
+	id          string
+	instanceURL string
+	user        struct {
 		id       string
 		name     string
 		fullName string
 		email    string
 	}
-	clientID      string
-	apiVersion    string
-	baseURL       string
-	instanceURL   string
-	useToolingAPI bool
-	httpClient    *http.Client
+	refreshToken  string
+	sharedToken   bool   // the refresh token belongs to another tool such as the Salesforce CLI, Logout keeps it.
+	oauthURL      string // login URL the OAuth session was issued by, used for refreshing.
+	oauthClientID string // client ID the OAuth session was issued to, used for refreshing.
+	identityURL   string
+	identity      *Identity // cached by Identity.
+	issuedAt      time.Time
 }
 
 // QueryResult holds the response data from an SOQL query.
@@ -54,43 +85,82 @@ type QueryResult struct {
 
 // Expose sid to save in admin settings
 func (client *Client) GetSid() (sid string) {
-	return client.sessionID
+	return client.currentSession().id
 }
 
 // Expose Loc to save in admin settings
 func (client *Client) GetLoc() (loc string) {
-	return client.instanceURL
+	return client.currentSession().instanceURL
 }
 
 // Set SID and Loc as a means to log in without LoginPassword
 func (client *Client) SetSidLoc(sid string, loc string) {
-	client.sessionID = sid
-	client.instanceURL = loc
+	client.setSession(session{id: sid, instanceURL: loc})
+	client.usage.reset()
+}
+
+// currentSession returns a copy of the session of the client.
+func (client *Client) currentSession() session {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+	return client.session
+}
+
+// setSession replaces the session of the client.
+func (client *Client) setSession(s session) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	client.session = s
 }
 
 // Query runs an SOQL query. q could either be the SOQL string or the nextRecordsURL.
 func (client *Client) Query(q string) (*QueryResult, error) {
+	return client.QueryContext(context.Background(), q)
+}
+
+// QueryContext is like Query, the request is bound to ctx.
+func (client *Client) QueryContext(ctx context.Context, q string) (*QueryResult, error) {
+	return client.query(ctx, "query", q)
+}
+
+// QueryAll is like Query, but the result includes the deleted records still in the recycle bin and the archived
+// records, e.g. with "SELECT Id, IsDeleted FROM Account". The Tooling API does not support it, ErrToolingUnsupported
+// is returned for a client returned by Tooling.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_queryall.htm
+func (client *Client) QueryAll(q string) (*QueryResult, error) {
+	return client.QueryAllContext(context.Background(), q)
+}
+
+// QueryAllContext is like QueryAll, the request is bound to ctx.
+func (client *Client) QueryAllContext(ctx context.Context, q string) (*QueryResult, error) {
+	if client.useToolingAPI {
+		return nil, ErrToolingUnsupported
+	}
+	return client.query(ctx, "queryAll", q)
+}
+
+// query runs the SOQL query q, or fetches the page at the nextRecordsURL q, with the query or queryAll resource.
+func (client *Client) query(ctx context.Context, resource, q string) (*QueryResult, error) {
 	if !client.isLoggedIn() {
 		return nil, ErrAuthentication
 	}
 
 	var u string
+	baseURL := client.currentSession().instanceURL
 	if strings.HasPrefix(q, "/services/data") {
 		// q is nextRecordsURL.
-		u = fmt.Sprintf("%s%s", client.instanceURL, q)
+		u = fmt.Sprintf("%s%s", baseURL, q)
 	} else {
 		// q is SOQL.
-		formatString := "%s/services/data/v%s/query?q=%s"
-		baseURL := client.instanceURL
 		if client.useToolingAPI {
-			formatString = strings.Replace(formatString, "query", "tooling/query", -1)
+			resource = "tooling/" + resource
 		}
-		u = fmt.Sprintf(formatString, baseURL, client.apiVersion, url.PathEscape(q))
+		u = fmt.Sprintf("%s/services/data/v%s/%s?q=%s", baseURL, client.version(), resource, url.PathEscape(q))
 	}
 
-	data, err := client.httpRequest("GET", u, nil)
+	data, err := client.httpRequest(ctx, "GET", u, nil)
 	if err != nil {
-		log.Println(logPrefix, "HTTP GET request failed:", u)
+		client.log(LevelError, "query failed", "url", u, "error", err)
 		return nil, err
 	}
 
@@ -110,15 +180,28 @@ func (client *Client) Query(q string) (*QueryResult, error) {
 
 // ApexREST executes a custom rest request with the provided method, path, and body. The path is relative to the domain.
 func (client *Client) ApexREST(method, path string, requestBody io.Reader) ([]byte, error) {
+	return client.ApexRESTContext(context.Background(), method, path, requestBody)
+}
+
+// ApexRESTContext is like ApexREST, the request is bound to ctx.
+func (client *Client) ApexRESTContext(ctx context.Context, method, path string, requestBody io.Reader) ([]byte, error) {
 	if !client.isLoggedIn() {
 		return nil, ErrAuthentication
 	}
 
-	u := fmt.Sprintf("%s/%s", client.instanceURL, path)
+	u := fmt.Sprintf("%s/%s", client.currentSession().instanceURL, path)
 
-	data, err := client.httpRequest(method, u, requestBody)
+	var body []byte
+	if requestBody != nil {
+		var err error
+		body, err = ioutil.ReadAll(requestBody)
+		if err != nil {
+			return nil, err
+		}
+	}
+	data, err := client.httpRequest(ctx, method, u, body)
 	if err != nil {
-		log.Println(logPrefix, fmt.Sprintf("HTTP %s request failed:", method), u)
+		client.log(LevelError, "apex rest request failed", "method", method, "url", u, "error", err)
 		return nil, err
 	}
 
@@ -137,58 +220,53 @@ func (client *Client) SObject(typeName ...string) *SObject {
 
 // isLoggedIn returns if the login to salesforce is successful.
 func (client *Client) isLoggedIn() bool {
-	return client.sessionID != ""
+	return client.currentSession().id != ""
 }
 
 type BearerTokenResponse struct {
-	AccessToken string `json:"access_token"`
-	InstanceURL string `json:"instance_url"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	InstanceURL  string `json:"instance_url"`
+	ID           string `json:"id"`
+	TokenType    string `json:"token_type"`
+	Scope        string `json:"scope"`
+	IssuedAt     string `json:"issued_at"`
+	Signature    string `json:"signature"`
 }
 
+// LoginWithAuthCode exchanges an authorization code obtained by the web server flow for a session. The refresh token
+// returned along with the session is kept so that the session can be renewed once it expires.
 func (client *Client) LoginWithAuthCode(loginURL string, code string) error {
-	endpoint := fmt.Sprintf(`%s/services/oauth2/token`, loginURL)
-	data := url.Values{}
-	data.Set("grant_type", "authorization_code")
-	data.Set("code", code)
-	data.Set("client_id", DefaultClientID)
-	data.Set("redirect_uri", DefaultRedirectURI)
-
-	c := &http.Client{}
-	r, err := http.NewRequest("POST", endpoint, strings.NewReader(data.Encode())) // URL-encoded payload
-	if err != nil {
-		log.Fatal(err)
-	}
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	r.Header.Add("Content-Length", strconv.Itoa(len(data.Encode())))
+	return client.LoginWithAuthCodeContext(context.Background(), loginURL, code)
+}
 
-	res, err := c.Do(r)
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Println(res.Status)
-	defer res.Body.Close()
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return err
-	}
+// LoginWithAuthCodeContext is like LoginWithAuthCode, the token request is bound to ctx.
+func (client *Client) LoginWithAuthCodeContext(ctx context.Context, loginURL string, code string) error {
 	//{"access_token":"00D8AXXXX","refresh_token":"5Aep861X_XXXX","signature":"1TYHTXXX","scope":"refresh_token web api id full","id_token":"eyJraWQiOiIyXXXX","instance_url":"https://computing-efficiency-2574-dev-ed.cs45.my.salesforce.com","id":"https://test.salesforce.com/id/00D8A000000MWVeUAO/0058A000008yLsfQAE","token_type":"Bearer","issued_at":"1636385425594"}
-	var response BearerTokenResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return fmt.Errorf("error unmarshalling response '%s': %s", body, err)
-	}
-	if response.AccessToken == "" {
-		return errors.New("Authentication failed")
-	}
-	client.sessionID = response.AccessToken
-	client.instanceURL = parseHost(response.InstanceURL)
-	return nil
+	return client.exchangeAuthCode(ctx, loginURL, DefaultClientID, DefaultRedirectURI, code, "")
+}
+
+// LoginWithRefreshToken signs into salesforce by redeeming a refresh token issued to the client ID of the client.
+// The refresh token is kept and used to renew the session automatically when it expires.
+// Ref: https://help.salesforce.com/s/articleView?id=sf.remoteaccess_oauth_refresh_token_flow.htm
+func (client *Client) LoginWithRefreshToken(loginURL string, refreshToken string) error {
+	return client.LoginWithRefreshTokenContext(context.Background(), loginURL, refreshToken)
+}
+
+// LoginWithRefreshTokenContext is like LoginWithRefreshToken, the token request is bound to ctx.
+func (client *Client) LoginWithRefreshTokenContext(ctx context.Context, loginURL string, refreshToken string) error {
+	return client.redeemRefreshToken(ctx, loginURL, client.clientID, refreshToken)
 }
 
 // LoginPassword signs into salesforce using password. token is optional if trusted IP is configured.
 // Ref: https://developer.salesforce.com/docs/atlas.en-us.214.0.api_rest.meta/api_rest/intro_understanding_username_password_oauth_flow.htm
 // Ref: https://developer.salesforce.com/docs/atlas.en-us.214.0.api.meta/api/sforce_api_calls_login.htm
 func (client *Client) LoginPassword(username, password, token string) error {
+	return client.LoginPasswordContext(context.Background(), username, password, token)
+}
+
+// LoginPasswordContext is like LoginPassword, the login request is bound to ctx.
+func (client *Client) LoginPasswordContext(ctx context.Context, username, password, token string) error {
 	// Use the SOAP interface to acquire session ID with username, password, and token.
 	// Do not use REST interface here as REST interface seems to have strong checking against client_id, while the SOAP
 	// interface allows a non-exist placeholder client_id to be used.
@@ -213,39 +291,35 @@ func (client *Client) LoginPassword(username, password, token string) error {
         </env:Envelope>`
 	soapBody = fmt.Sprintf(soapBody, client.clientID, username, html.EscapeString(password), token)
 
-	url := fmt.Sprintf("%s/services/Soap/u/%s", client.baseURL, client.apiVersion)
-	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(soapBody))
+	url := fmt.Sprintf("%s/services/Soap/u/%s", client.baseURL, client.version())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(soapBody))
 	if err != nil {
-		log.Println(logPrefix, "error occurred creating request,", err)
+		client.log(LevelError, "error occurred creating request", "error", err)
 		return err
 	}
 	req.Header.Add("Content-Type", "text/xml")
 	req.Header.Add("charset", "UTF-8")
 	req.Header.Add("SOAPAction", "login")
 
-	resp, err := client.httpClient.Do(req)
+	resp, err := client.do(req)
 	if err != nil {
-		log.Println(logPrefix, "error occurred submitting request,", err)
+		client.log(LevelError, "error occurred submitting login request", "error", err)
+		return err
+	}
+	respData, err := ioutil.ReadAll(resp.Body)
+	// The open body holds a slot of SetMaxConcurrentRequests, which the API version lookup below needs.
+	resp.Body.Close()
+	if err != nil {
+		client.log(LevelError, "error occurred reading response data", "error", err)
 		return err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		log.Println(logPrefix, "request failed,", resp.StatusCode)
-		buf := new(bytes.Buffer)
-		buf.ReadFrom(resp.Body)
-		newStr := buf.String()
-		log.Println(logPrefix, "Failed resp.body: ", newStr)
-		theError := ParseSalesforceError(resp.StatusCode, buf.Bytes())
+		client.log(LevelError, "login failed", "status", resp.StatusCode, "body", respData)
+		theError := ParseSalesforceError(resp.StatusCode, respData)
 		return theError
 	}
 
-	respData, err := ioutil.ReadAll(resp.Body)
-
-	if err != nil {
-		log.Println(logPrefix, "error occurred reading response data,", err)
-	}
-
 	var loginResponse struct {
 		XMLName      xml.Name `xml:"Envelope"`
 		ServerURL    string   `xml:"Body>loginResponse>result>serverUrl"`
@@ -258,66 +332,198 @@ func (client *Client) LoginPassword(username, password, token string) error {
 
 	err = xml.Unmarshal(respData, &loginResponse)
 	if err != nil {
-		log.Println(logPrefix, "error occurred parsing login response,", err)
+		client.log(LevelError, "error occurred parsing login response", "error", err)
 		return err
 	}
 
 	// Now we should all be good and the sessionID can be used to talk to salesforce further.
-	client.sessionID = loginResponse.SessionID
-	client.instanceURL = parseHost(loginResponse.ServerURL)
-	client.user.id = loginResponse.UserID
-	client.user.name = loginResponse.UserName
-	client.user.email = loginResponse.UserEmail
-	client.user.fullName = loginResponse.UserFullName
-
-	log.Println(logPrefix, "User", client.user.name, "authenticated.")
-	return nil
+	s := session{
+		id:          loginResponse.SessionID,
+		instanceURL: parseHost(loginResponse.ServerURL),
+		issuedAt:    time.Now(),
+	}
+	s.user.id = loginResponse.UserID
+	s.user.name = loginResponse.UserName
+	s.user.email = loginResponse.UserEmail
+	s.user.fullName = loginResponse.UserFullName
+	client.setSession(s)
+	client.ensureAPIVersion(ctx)
+
+	client.log(LevelInfo, "user authenticated", "user", s.user.name)
+	return client.saveToken()
 }
 
-// httpRequest executes an HTTP request to the salesforce server and returns the response data in byte buffer.
-func (client *Client) httpRequest(method, url string, body io.Reader) ([]byte, error) {
-	req, err := http.NewRequest(method, url, body)
-	if err != nil {
-		return nil, err
+// Logout invalidates the session of the client: OAuth sessions are revoked via the revoke endpoint (the refresh token if
+// any, which also revokes its access tokens), sessions obtained by LoginPassword are ended with the SOAP logout call.
+// The refresh token of a client created by NewClientFromAuthURL is shared with the Salesforce CLI, so only its access
+// token is revoked.
+// The session is cleared from the client and its token store even if invalidating it on the server fails, in which
+// case the error is returned.
+// Ref: https://help.salesforce.com/s/articleView?id=sf.remoteaccess_revoke_token.htm
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api.meta/api/sforce_api_calls_logout.htm
+func (client *Client) Logout() error {
+	return client.LogoutContext(context.Background())
+}
+
+// LogoutContext is like Logout, the requests invalidating the session are bound to ctx.
+func (client *Client) LogoutContext(ctx context.Context) error {
+	if !client.isLoggedIn() {
+		return nil
+	}
+
+	var err error
+	s := client.currentSession()
+	switch {
+	case s.refreshToken != "" && !s.sharedToken:
+		err = client.revokeToken(ctx, s.refreshToken)
+	case s.oauthURL != "":
+		err = client.revokeToken(ctx, s.id)
+	default:
+		err = client.soapLogout(ctx)
 	}
 
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", client.sessionID))
-	req.Header.Add("Content-Type", "application/json")
+	client.clearSession()
+	if client.tokenStore != nil {
+		if clearErr := client.tokenStore.Clear(); err == nil {
+			err = clearErr
+		}
+	}
+	return err
+}
 
-	resp, err := client.httpClient.Do(req)
+// soapLogout ends the session with the SOAP logout call.
+func (client *Client) soapLogout(ctx context.Context) error {
+	soapBody := `<?xml version="1.0" encoding="utf-8" ?>
+        <env:Envelope
+                xmlns:env="http://schemas.xmlsoap.org/soap/envelope/"
+                xmlns:urn="urn:partner.soap.sforce.com">
+            <env:Header>
+                <urn:SessionHeader>
+                    <urn:sessionId>%s</urn:sessionId>
+                </urn:SessionHeader>
+            </env:Header>
+            <env:Body>
+                <urn:logout/>
+            </env:Body>
+        </env:Envelope>`
+	s := client.currentSession()
+	soapBody = fmt.Sprintf(soapBody, html.EscapeString(s.id))
+
+	url := fmt.Sprintf("%s/services/Soap/u/%s", s.instanceURL, client.version())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(soapBody))
 	if err != nil {
-		return nil, err
+		return err
+	}
+	req.Header.Add("Content-Type", "text/xml")
+	req.Header.Add("charset", "UTF-8")
+	req.Header.Add("SOAPAction", "logout")
+
+	resp, err := client.do(req)
+	if err != nil {
+		client.log(LevelError, "error occurred submitting logout request", "error", err)
+		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		log.Println(logPrefix, "request failed,", resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		client.log(LevelError, "logout failed", "status", resp.StatusCode)
 		buf := new(bytes.Buffer)
 		buf.ReadFrom(resp.Body)
-		newStr := buf.String()
-		theError := ParseSalesforceError(resp.StatusCode, buf.Bytes())
-		log.Println(logPrefix, "Failed resp.body: ", newStr)
-		return nil, theError
+		return ParseSalesforceError(resp.StatusCode, buf.Bytes())
 	}
+	return nil
+}
 
-	return ioutil.ReadAll(resp.Body)
+// clearSession forgets the session and the user of the client.
+func (client *Client) clearSession() {
+	client.setSession(session{})
+	client.usage.reset()
 }
 
-// makeURL generates a REST API URL based on baseURL, APIVersion of the client.
+// httpRequest executes an HTTP request to the salesforce server and returns the response data in byte buffer.
+// If the session has expired and a refresh token is available, the session is renewed and the request retried once.
+// Transient failures are retried according to the retry policy of the client.
+func (client *Client) httpRequest(ctx context.Context, method, url string, body []byte) ([]byte, error) {
+	started := time.Now()
+	refreshed := false
+	idempotent := isIdempotent(ctx, method)
+	for attempt := 1; ; attempt++ {
+		if err := client.usage.check(ctx); err != nil {
+			return nil, err
+		}
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+		if err != nil {
+			return nil, err
+		}
+
+		s := client.currentSession()
+		if s.id != "" {
+			req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", s.id))
+		}
+		req.Header.Add("Content-Type", "application/json")
+
+		resp, err := client.do(req)
+		if err != nil {
+			if delay, ok := client.retryPolicy.backoff(attempt, started, 0); ok && isRetryableError(err, idempotent) {
+				client.log(LevelWarn, "request failed, retrying", "method", method, "url", url, "delay", delay, "error", err)
+				if err := sleepContext(ctx, delay); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, err
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			buf := new(bytes.Buffer)
+			buf.ReadFrom(resp.Body)
+			resp.Body.Close()
+			if !refreshed && s.refreshToken != "" && isInvalidSession(resp.StatusCode, buf.Bytes()) {
+				refreshed = true
+				if client.refreshSession(ctx, s.id) == nil {
+					continue
+				}
+			}
+			if delay, ok := client.retryPolicy.backoff(attempt, started, retryAfter(resp)); ok &&
+				isRetryableResponse(resp.StatusCode, buf.Bytes(), idempotent) {
+				client.log(LevelWarn, "request failed, retrying", "method", method, "url", url, "status", resp.StatusCode,
+					"delay", delay)
+				if err := sleepContext(ctx, delay); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			client.log(LevelError, "request failed", "method", method, "url", url, "status", resp.StatusCode)
+			client.log(LevelDebug, "failed response", "body", buf.Bytes())
+			return nil, ParseSalesforceError(resp.StatusCode, buf.Bytes())
+		}
+
+		defer resp.Body.Close()
+		return ioutil.ReadAll(resp.Body)
+	}
+}
+
+// makeURL generates a REST API URL based on the instance URL and APIVersion of the client.
 func (client *Client) makeURL(req string) string {
-	client.apiVersion = strings.Replace(client.apiVersion, "v", "", -1)
-	retURL := fmt.Sprintf("%s/services/data/v%s/%s", client.instanceURL, client.apiVersion, req)
+	retURL := fmt.Sprintf("%s/services/data/v%s/%s", client.currentSession().instanceURL, client.version(), req)
 	return retURL
 }
 
-// NewClient creates a new instance of the client.
+// NewClient creates a new instance of the client. If apiVersion is empty, the client uses the latest version supported
+// by the org once logged in.
 func NewClient(url, clientID, apiVersion string) *Client {
-	client := &Client{
-		apiVersion: apiVersion,
+	client := &Client{clientState: &clientState{
+		apiVersion: strings.TrimPrefix(apiVersion, "v"),
 		baseURL:    url,
 		clientID:   clientID,
 		httpClient: &http.Client{},
-	}
+		logger:     nopLogger{},
+		usage:      &apiUsageTracker{},
+	}}
 
 	// Remove trailing "/" from base url to prevent "//" when paths are appended
 	if strings.HasSuffix(client.baseURL, "/") {
@@ -330,24 +536,44 @@ func (client *Client) SetHttpClient(c *http.Client) {
 	client.httpClient = c
 }
 
+// SetClientSecret sets the consumer secret of the connected app, which is sent along with the client ID by the OAuth
+// flows if the connected app requires it. It is not sent by the flows using another client ID, such as
+// LoginWithAuthCode.
+func (client *Client) SetClientSecret(secret string) {
+	client.clientSecret = secret
+}
+
 // DownloadFile downloads a file based on the REST API path given. Saves to filePath.
 func (client *Client) DownloadFile(contentVersionID string, filepath string) error {
+	return client.DownloadFileContext(context.Background(), contentVersionID, filepath)
+}
 
-	apiPath := fmt.Sprintf("/services/data/v%s/sobjects/ContentVersion/%s/VersionData", client.apiVersion, contentVersionID)
+// DownloadFileContext is like DownloadFile, the download is bound to ctx.
+func (client *Client) DownloadFileContext(ctx context.Context, contentVersionID string, filepath string) error {
+	if err := client.usage.check(ctx); err != nil {
+		return err
+	}
+
+	apiPath := fmt.Sprintf("/services/data/v%s/sobjects/ContentVersion/%s/VersionData", client.version(), contentVersionID)
 
 	// Get the data
-	httpClient := client.httpClient
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s%s", strings.TrimRight(client.instanceURL, "/"), apiPath), nil)
+	s := client.currentSession()
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s%s", strings.TrimRight(s.instanceURL, "/"), apiPath), nil)
 	req.Header.Add("Content-Type", "application/json; charset=UTF-8")
 	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Authorization", "Bearer "+client.sessionID)
+	req.Header.Add("Authorization", "Bearer "+s.id)
 
-	resp, err := httpClient.Do(req)
+	resp, err := client.do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		respData, _ := ioutil.ReadAll(resp.Body)
+		return ParseSalesforceError(resp.StatusCode, respData)
+	}
+
 	// Create the file
 	out, err := os.Create(filepath)
 	if err != nil {
@@ -370,16 +596,23 @@ func parseHost(input string) string {
 
 // Get the List of all available objects and their metadata for your organization's data
 func (client *Client) DescribeGlobal() (*SObjectMeta, error) {
-	apiPath := fmt.Sprintf("/services/data/v%s/sobjects", client.apiVersion)
+	return client.DescribeGlobalContext(context.Background())
+}
+
+// DescribeGlobalContext is like DescribeGlobal, the request is bound to ctx.
+func (client *Client) DescribeGlobalContext(ctx context.Context) (*SObjectMeta, error) {
+	if err := client.usage.check(ctx); err != nil {
+		return nil, err
+	}
+	apiPath := fmt.Sprintf("/services/data/v%s/sobjects", client.version())
 	baseURL := strings.TrimRight(client.baseURL, "/")
 	url := fmt.Sprintf("%s%s", baseURL, apiPath) // Get the objects
-	httpClient := client.httpClient
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	req.Header.Add("Content-Type", "application/json; charset=UTF-8")
 	req.Header.Add("Accept", "application/json")
-	req.Header.Add("Authorization", "Bearer "+client.sessionID)
+	req.Header.Add("Authorization", "Bearer "+client.currentSession().id)
 	// resp, err := http.Get(url)
-	resp, err := httpClient.Do(req)
+	resp, err := client.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -388,9 +621,11 @@ func (client *Client) DescribeGlobal() (*SObjectMeta, error) {
 	var meta SObjectMeta
 
 	respData, err := ioutil.ReadAll(resp.Body)
-	log.Println(logPrefix, fmt.Sprintf("status code %d", resp.StatusCode))
 	if err != nil {
-		log.Println(logPrefix, "error while reading all body")
+		client.log(LevelError, "error while reading all body", "error", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, ParseSalesforceError(resp.StatusCode, respData)
 	}
 
 	err = json.Unmarshal(respData, &meta)
@@ -1,10 +1,21 @@
 package simpleforce
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/veloceapps/simpleforce/simpleforcetest"
 )
 
 var (
@@ -20,6 +31,13 @@ var (
 	}()
 )
 
+// cassetteURL, cassetteUser and cassettePass replace the org URLs and credentials in the cassettes of the tests.
+const (
+	cassetteURL  = "https://example.my.salesforce.com"
+	cassetteUser = "user@example.com"
+	cassettePass = "password"
+)
+
 func checkCredentialsAndSkip(t *testing.T) {
 	if sfUser == "" || sfPass == "" {
 		log.Println(logPrefix, "SF_USER, SF_PASS environment variables are not set.")
@@ -27,16 +45,59 @@ func checkCredentialsAndSkip(t *testing.T) {
 	}
 }
 
-func requireClient(t *testing.T, skippable bool) *Client {
-	if skippable {
-		checkCredentialsAndSkip(t)
+// cassettePath returns the cassette of the test. The checked-in cassettes were recorded from simpleforcetest.Server,
+// see testdata/cassettes/README.md.
+func cassettePath(t *testing.T) string {
+	return filepath.Join("testdata", "cassettes", strings.Replace(t.Name(), "/", "_", -1)+".json")
+}
+
+// newTestClient returns a client of the org set with SF_URL, and the credentials to log in with. Without SF_USER and
+// SF_PASS, the client replays the cassette of the test, and the test is skipped if it has none. With SF_RECORD set,
+// the traffic with the org is recorded to the cassette of the test.
+func newTestClient(t *testing.T, skippable bool) (client *Client, user, pass, token string) {
+	if sfUser == "" || sfPass == "" {
+		path := cassettePath(t)
+		if _, err := os.Stat(path); err != nil && skippable {
+			checkCredentialsAndSkip(t)
+		}
+		recorder, err := simpleforcetest.NewRecorder(path, simpleforcetest.ModeReplay, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client = NewClient(cassetteURL, DefaultClientID, DefaultAPIVersion)
+		client.SetHttpClient(recorder.Client())
+		return client, cassetteUser, cassettePass, ""
 	}
 
-	client := NewClient(sfURL, DefaultClientID, DefaultAPIVersion)
-	if client == nil {
-		t.Fail()
+	client = NewClient(sfURL, DefaultClientID, DefaultAPIVersion)
+	if os.Getenv("SF_RECORD") == "" {
+		return client, sfUser, sfPass, sfToken
 	}
-	err := client.LoginPassword(sfUser, sfPass, sfToken)
+
+	// The instance URL is only known after a login.
+	probe := NewClient(sfURL, DefaultClientID, DefaultAPIVersion)
+	if err := probe.LoginPassword(sfUser, sfPass, sfToken); err != nil {
+		t.Fatal(err)
+	}
+	recorder, err := simpleforcetest.NewRecorder(cassettePath(t), simpleforcetest.ModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.ScrubString(probe.GetLoc(), cassetteURL)
+	recorder.ScrubString(strings.TrimSuffix(sfURL, "/"), cassetteURL)
+	recorder.ScrubString(sfUser, cassetteUser)
+	t.Cleanup(func() {
+		if err := recorder.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+	client.SetHttpClient(recorder.Client())
+	return client, sfUser, sfPass, sfToken
+}
+
+func requireClient(t *testing.T, skippable bool) *Client {
+	client, user, pass, token := newTestClient(t, skippable)
+	err := client.LoginPassword(user, pass, token)
 	if err != nil {
 		t.Fatal()
 	}
@@ -44,19 +105,14 @@ func requireClient(t *testing.T, skippable bool) *Client {
 }
 
 func TestClient_LoginPassword(t *testing.T) {
-	checkCredentialsAndSkip(t)
-
-	client := NewClient(sfURL, DefaultClientID, DefaultAPIVersion)
-	if client == nil {
-		t.Fatal()
-	}
+	client, user, pass, token := newTestClient(t, true)
 
 	// Use token
-	err := client.LoginPassword(sfUser, sfPass, sfToken)
+	err := client.LoginPassword(user, pass, token)
 	if err != nil {
 		t.Fail()
 	} else {
-		log.Println(logPrefix, "sessionID:", client.sessionID)
+		log.Println(logPrefix, "sessionID:", client.GetSid())
 	}
 
 	err = client.LoginPassword("__INVALID_USER__", "__INVALID_PASS__", "__INVALID_TOKEN__")
@@ -66,19 +122,14 @@ func TestClient_LoginPassword(t *testing.T) {
 }
 
 func TestClient_LoginPasswordNoToken(t *testing.T) {
-	checkCredentialsAndSkip(t)
-
-	client := NewClient(sfURL, DefaultClientID, DefaultAPIVersion)
-	if client == nil {
-		t.Fatal()
-	}
+	client, user, pass, _ := newTestClient(t, true)
 
 	// Trusted IP must be configured AND the request must be initiated from the trusted IP range.
-	err := client.LoginPassword(sfUser, sfPass, "")
+	err := client.LoginPassword(user, pass, "")
 	if err != nil {
 		t.FailNow()
 	} else {
-		log.Println(logPrefix, "sessionID:", client.sessionID)
+		log.Println(logPrefix, "sessionID:", client.GetSid())
 	}
 }
 
@@ -154,6 +205,198 @@ func TestClient_QueryLike(t *testing.T) {
 	}
 }
 
+func TestClient_Logout(t *testing.T) {
+	revoked := map[string]bool{}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/services/oauth2/token":
+			fmt.Fprintf(w, `{"access_token":"__OAUTH_SESSION__","refresh_token":"%s","instance_url":"%s"}`,
+				r.FormValue("refresh_token"), server.URL)
+		case "/services/oauth2/revoke":
+			if r.FormValue("token") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"unsupported_token_type","error_description":"this token type is not supported"}`)
+				return
+			}
+			revoked[r.FormValue("token")] = true
+		case "/services/Soap/u/" + DefaultAPIVersion:
+			body, _ := ioutil.ReadAll(r.Body)
+			if r.Header.Get("SOAPAction") != "logout" || !strings.Contains(string(body), "__SOAP_SESSION__") {
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprint(w, `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body><soapenv:Fault><faultcode>sf:INVALID_SESSION_ID</faultcode><faultstring>INVALID_SESSION_ID: Invalid Session ID</faultstring></soapenv:Fault></soapenv:Body></soapenv:Envelope>`)
+				return
+			}
+			revoked["__SOAP_SESSION__"] = true
+			fmt.Fprint(w, `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body><logoutResponse/></soapenv:Body></soapenv:Envelope>`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// OAuth session with a refresh token.
+	store := NewMemoryTokenStore()
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	client.SetTokenStore(store)
+	if err := client.LoginWithRefreshToken(server.URL, "__REFRESH__"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Logout(); err != nil {
+		t.Fatal(err)
+	}
+	if !revoked["__REFRESH__"] || client.isLoggedIn() || client.currentSession().refreshToken != "" {
+		t.Fail()
+	}
+	if token, _ := store.Load(); token != nil {
+		t.Fail()
+	}
+
+	// Session set directly is ended with the SOAP logout call.
+	client.SetSidLoc("__SOAP_SESSION__", server.URL)
+	if err := client.Logout(); err != nil {
+		t.Fatal(err)
+	}
+	if !revoked["__SOAP_SESSION__"] || client.isLoggedIn() {
+		t.Fail()
+	}
+
+	// Logging out without a session is a no-op.
+	if client.Logout() != nil {
+		t.Fail()
+	}
+
+	// Session is cleared even if the server rejects the logout.
+	client.SetSidLoc("__UNKNOWN_SESSION__", server.URL)
+	if client.Logout() == nil || client.isLoggedIn() {
+		t.Fail()
+	}
+}
+
+func TestClient_QueryContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") == "SELECT Id FROM Case" {
+			fmt.Fprint(w, `{"totalSize":1,"done":true,"records":[{"attributes":{"type":"Case"},"Id":"__ID__"}]}`)
+			return
+		}
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	client.SetSidLoc("__SESSION__", server.URL)
+
+	result, err := client.QueryContext(context.Background(), "SELECT Id FROM Case")
+	if err != nil || len(result.Records) != 1 || result.Records[0].client() != client {
+		t.Fail()
+	}
+
+	// The request is abandoned once the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = client.QueryContext(ctx, "SELECT Id FROM Account")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fail()
+	}
+}
+
+func TestClient_QueryAll(t *testing.T) {
+	server := simpleforcetest.NewServer()
+	defer server.Close()
+	server.Insert("Account", map[string]interface{}{"Name": "Active"})
+	server.Insert("Account", map[string]interface{}{"Name": "Deleted", "IsDeleted": true})
+
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	client.SetSidLoc(server.NewSession(), server.URL)
+
+	// Deleted records are only returned by QueryAll.
+	q := "SELECT Id, Name, IsDeleted FROM Account ORDER BY Name"
+	result, err := client.Query(q)
+	if err != nil || result.TotalSize != 1 {
+		t.Fatalf("unexpected %+v %v", result, err)
+	}
+	result, err = client.QueryAll(q)
+	if err != nil || result.TotalSize != 2 || result.Records[1].InterfaceField("IsDeleted") != true ||
+		result.Records[1].client() != client {
+		t.Fatalf("unexpected %+v %v", result, err)
+	}
+	result, err = client.QueryAll("SELECT Id FROM Account WHERE IsDeleted = true")
+	if err != nil || result.TotalSize != 1 {
+		t.Errorf("unexpected %+v %v", result, err)
+	}
+
+	// Negative: the Tooling API has no queryAll resource.
+	if _, err = client.Tooling().QueryAll(q); err != ErrToolingUnsupported {
+		t.Errorf("unexpected %v", err)
+	}
+}
+
+func TestClient_ConcurrentUse(t *testing.T) {
+	var mu sync.Mutex
+	issued := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/services/oauth2/token":
+			issued++
+			fmt.Fprintf(w, `{"access_token":"__SESSION_%d__","refresh_token":"__REFRESH__","instance_url":"%s"}`,
+				issued, server.URL)
+		case "/services/data/v" + DefaultAPIVersion + "/query", "/services/data/v" + DefaultAPIVersion + "/tooling/query":
+			if r.Header.Get("Authorization") != fmt.Sprintf("Bearer __SESSION_%d__", issued) {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `[{"message":"Session expired or invalid","errorCode":"INVALID_SESSION_ID"}]`)
+				return
+			}
+			fmt.Fprintf(w, `{"totalSize":1,"done":true,"records":[{"attributes":{"type":"Case"},"Path":"%s"}]}`, r.URL.Path)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, DefaultClientID, "v"+DefaultAPIVersion)
+	if err := client.LoginWithRefreshToken(server.URL, "__REFRESH__"); err != nil {
+		t.Fatal(err)
+	}
+	tooling := client.Tooling()
+	if client.useToolingAPI || !tooling.useToolingAPI || tooling.GetSid() != client.GetSid() {
+		t.Fatal("tooling view is not independent")
+	}
+
+	// Expire the session: all requests fail once and share a single refresh.
+	mu.Lock()
+	issued++
+	mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			result, err := client.Query("SELECT Id FROM Case")
+			if err != nil || result.Records[0].StringField("Path") != "/services/data/v"+DefaultAPIVersion+"/query" {
+				t.Errorf("unexpected %v %v", result, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			result, err := tooling.Query("SELECT Id FROM Layout")
+			if err != nil || result.Records[0].StringField("Path") != "/services/data/v"+DefaultAPIVersion+"/tooling/query" {
+				t.Errorf("unexpected %v %v", result, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if issued != 3 || tooling.GetSid() != "__SESSION_3__" {
+		t.Errorf("unexpected %d sessions issued", issued)
+	}
+}
+
 func TestMain(m *testing.M) {
 	m.Run()
 }
@@ -0,0 +1,122 @@
+package simpleforce
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Request headers understood by the REST API.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/headers.htm
+const (
+	HeaderCallOptions         = "Sforce-Call-Options"
+	HeaderQueryOptions        = "Sforce-Query-Options"
+	HeaderAutoAssign          = "Sforce-Auto-Assign"
+	HeaderDuplicateRuleHeader = "Sforce-Duplicate-Rule-Header"
+)
+
+// headersKey is the context key of the headers added by WithHeader.
+type headersKey struct{}
+
+// SetHeader adds a header to every request of the client, e.g. Sforce-Call-Options or Accept-Encoding: gzip.
+// Compressed responses are decompressed transparently. Headers bound to the context of a call with WithHeader take
+// precedence, and neither replaces the headers set by the client itself, such as Authorization and Content-Type.
+func (client *Client) SetHeader(key, value string) {
+	if client.headers == nil {
+		client.headers = http.Header{}
+	}
+	client.headers.Set(key, value)
+}
+
+// WithHeader returns a copy of ctx adding a header to the REST API requests of the calls bound to it. Logins and
+// token requests are sent without it.
+func WithHeader(ctx context.Context, key, value string) context.Context {
+	headers := http.Header{}
+	if parent, ok := ctx.Value(headersKey{}).(http.Header); ok {
+		headers = parent.Clone()
+	}
+	headers.Set(key, value)
+	return context.WithValue(ctx, headersKey{}, headers)
+}
+
+// WithQueryBatchSize returns a copy of ctx requesting query results in batches of size records, between 200 and
+// 2000, instead of the default 2000.
+func WithQueryBatchSize(ctx context.Context, size int) context.Context {
+	return WithHeader(ctx, HeaderQueryOptions, "batchSize="+strconv.Itoa(size))
+}
+
+// WithCallOptions returns a copy of ctx identifying the calls bound to it with clientID, e.g. a partner application ID.
+func WithCallOptions(ctx context.Context, clientID string) context.Context {
+	return WithHeader(ctx, HeaderCallOptions, "client="+clientID)
+}
+
+// WithAutoAssign returns a copy of ctx enabling or disabling the active assignment rules for the cases and leads
+// created or updated by the calls bound to it.
+func WithAutoAssign(ctx context.Context, enabled bool) context.Context {
+	return WithHeader(ctx, HeaderAutoAssign, strings.ToUpper(strconv.FormatBool(enabled)))
+}
+
+// WithDuplicateRuleHeader returns a copy of ctx controlling the duplicate rules applied to the records saved by the
+// calls bound to it. allowSave saves records even if they are detected as duplicates; includeRecordDetails returns the
+// fields of the duplicates; runAsCurrentUser enforces the sharing rules of the user when searching duplicates.
+func WithDuplicateRuleHeader(ctx context.Context, allowSave, includeRecordDetails, runAsCurrentUser bool) context.Context {
+	value := "allowSave=" + strconv.FormatBool(allowSave) +
+		"; includeRecordDetails=" + strconv.FormatBool(includeRecordDetails) +
+		"; runAsCurrentUser=" + strconv.FormatBool(runAsCurrentUser)
+	return WithHeader(ctx, HeaderDuplicateRuleHeader, value)
+}
+
+// applyHeaders adds the headers of the context of req, for REST API requests, and of the client to req. The headers
+// set by the request itself, such as Authorization and Content-Type, are kept.
+func (client *Client) applyHeaders(req *http.Request) {
+	if headers, ok := req.Context().Value(headersKey{}).(http.Header); ok && strings.HasPrefix(req.URL.Path, "/services/data") {
+		addHeaders(req.Header, headers)
+	}
+	addHeaders(req.Header, client.headers)
+}
+
+// addHeaders adds the headers of src which dst does not have to dst.
+func addHeaders(dst, src http.Header) {
+	for key, values := range src {
+		if _, ok := dst[key]; !ok {
+			dst[key] = append([]string(nil), values...)
+		}
+	}
+}
+
+// gzipBody closes both the decompressing reader and the underlying body.
+type gzipBody struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (body *gzipBody) Close() error {
+	body.Reader.Close()
+	return body.body.Close()
+}
+
+// decompress replaces the body of a gzip encoded response with the decompressed content. The HTTP client only does
+// that by itself if it added the Accept-Encoding header.
+func decompress(resp *http.Response) error {
+	if !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return nil
+	}
+	reader, err := gzip.NewReader(resp.Body)
+	switch {
+	case err == io.EOF:
+		// Empty body, e.g. 204 No Content.
+	case err != nil:
+		resp.Body.Close()
+		return err
+	default:
+		resp.Body = &gzipBody{Reader: reader, body: resp.Body}
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
@@ -0,0 +1,103 @@
+package simpleforce
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_Headers(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		body := []byte(`{"totalSize":0,"done":true,"records":[]}`)
+		if r.Header.Get("Accept-Encoding") == "gzip" {
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			gz.Write(body)
+			gz.Close()
+			body = buf.Bytes()
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	client.SetSidLoc("__SESSION__", server.URL)
+	client.SetHeader(HeaderCallOptions, "client=default")
+	client.SetHeader("Accept-Encoding", "gzip")
+
+	// The headers of the client are sent, and the compressed response is decoded.
+	result, err := client.Query("SELECT Id FROM Case")
+	if err != nil || result == nil || !result.Done {
+		t.Fatalf("unexpected %+v %v", result, err)
+	}
+	if got.Get(HeaderCallOptions) != "client=default" || got.Get("Authorization") != "Bearer __SESSION__" {
+		t.Errorf("unexpected headers %v", got)
+	}
+
+	// The headers of the context are added and take precedence.
+	ctx := WithQueryBatchSize(context.Background(), 500)
+	ctx = WithCallOptions(ctx, "partner")
+	ctx = WithAutoAssign(ctx, false)
+	ctx = WithDuplicateRuleHeader(ctx, true, false, true)
+	if _, err = client.QueryContext(ctx, "SELECT Id FROM Case"); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		HeaderQueryOptions:        "batchSize=500",
+		HeaderCallOptions:         "client=partner",
+		HeaderAutoAssign:          "FALSE",
+		HeaderDuplicateRuleHeader: "allowSave=true; includeRecordDetails=false; runAsCurrentUser=true",
+	}
+	for key, value := range expected {
+		if got.Get(key) != value {
+			t.Errorf("%s: expected %q, got %q", key, value, got.Get(key))
+		}
+	}
+
+	// The headers of a context are not shared with its parent.
+	parent := WithHeader(context.Background(), "X-Test", "parent")
+	WithHeader(parent, "X-Test", "child")
+	if _, err = client.QueryContext(parent, "SELECT Id FROM Case"); err != nil || got.Get("X-Test") != "parent" {
+		t.Errorf("unexpected %q %v", got.Get("X-Test"), err)
+	}
+
+	// Negative: the headers of the client and of the context do not replace the ones of the request.
+	client.SetHeader("Authorization", "Bearer __OTHER__")
+	ctx = WithHeader(ctx, "Content-Type", "text/plain")
+	if _, err = client.SObject("Case").Set("Subject", "x").CreateContext(ctx); err == nil {
+		t.Error("unexpected record created")
+	}
+	if got.Get("Authorization") != "Bearer __SESSION__" || got.Get("Content-Type") != "application/json" ||
+		got.Get(HeaderQueryOptions) != "batchSize=500" {
+		t.Errorf("unexpected headers %v", got)
+	}
+
+	// Negative: the headers of the context are not sent with logins.
+	client.LoginPasswordContext(ctx, "user@example.com", "password", "")
+	if got.Get(HeaderQueryOptions) != "" || got.Get("Content-Type") != "text/xml" {
+		t.Errorf("unexpected headers %v", got)
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	// An empty compressed body is accepted.
+	resp := &http.Response{Header: http.Header{"Content-Encoding": {"gzip"}}, Body: http.NoBody}
+	if err := decompress(resp); err != nil || resp.Header.Get("Content-Encoding") != "" {
+		t.Errorf("unexpected %v", err)
+	}
+
+	// Negative: a corrupt body fails.
+	recorder := httptest.NewRecorder()
+	recorder.Header().Set("Content-Encoding", "gzip")
+	fmt.Fprint(recorder, "not gzip")
+	if err := decompress(recorder.Result()); err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,126 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Identity describes the user and org a session belongs to.
+// Ref: https://help.salesforce.com/s/articleView?id=sf.remoteaccess_using_openid.htm
+type Identity struct {
+	IdentityURL    string            `json:"identityUrl"`
+	UserID         string            `json:"userId"`
+	OrganizationID string            `json:"organizationId"`
+	Username       string            `json:"username"`
+	DisplayName    string            `json:"displayName"`
+	Email          string            `json:"email"`
+	Locale         string            `json:"locale"`
+	Language       string            `json:"language"`
+	Timezone       string            `json:"timezone"`
+	UserType       string            `json:"userType"`
+	URLs           map[string]string `json:"urls"`
+}
+
+// identityResponse covers both the identity URL and the OpenID Connect userinfo response, which name some of the
+// attributes differently.
+type identityResponse struct {
+	ID                string            `json:"id"`
+	Sub               string            `json:"sub"`
+	UserID            string            `json:"user_id"`
+	OrganizationID    string            `json:"organization_id"`
+	Username          string            `json:"username"`
+	PreferredUsername string            `json:"preferred_username"`
+	DisplayName       string            `json:"display_name"`
+	Name              string            `json:"name"`
+	Email             string            `json:"email"`
+	Locale            string            `json:"locale"`
+	Language          string            `json:"language"`
+	Timezone          string            `json:"timezone"`
+	ZoneInfo          string            `json:"zoneinfo"`
+	UserType          string            `json:"user_type"`
+	URLs              map[string]string `json:"urls"`
+}
+
+// Identity returns the user and org of the session, whichever way it was obtained. The identity URL returned with an
+// OAuth token is used if known, the userinfo endpoint of the instance otherwise. The result is cached on the client
+// until the next login.
+func (client *Client) Identity() (*Identity, error) {
+	return client.IdentityContext(context.Background())
+}
+
+// IdentityContext is like Identity, the lookup is bound to ctx.
+func (client *Client) IdentityContext(ctx context.Context) (*Identity, error) {
+	if !client.isLoggedIn() {
+		return nil, ErrAuthentication
+	}
+	s := client.currentSession()
+	if s.identity != nil {
+		return s.identity.clone(), nil
+	}
+
+	endpoint := s.identityURL
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("%s/services/oauth2/userinfo", s.instanceURL)
+	}
+	data, err := client.httpRequest(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		client.log(LevelError, "identity request failed", "url", endpoint, "error", err)
+		return nil, err
+	}
+
+	var resp identityResponse
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, err
+	}
+	identity := &Identity{
+		IdentityURL:    firstNonEmpty(resp.ID, resp.Sub),
+		UserID:         resp.UserID,
+		OrganizationID: resp.OrganizationID,
+		Username:       firstNonEmpty(resp.Username, resp.PreferredUsername),
+		DisplayName:    firstNonEmpty(resp.DisplayName, resp.Name),
+		Email:          resp.Email,
+		Locale:         resp.Locale,
+		Language:       resp.Language,
+		Timezone:       firstNonEmpty(resp.Timezone, resp.ZoneInfo),
+		UserType:       resp.UserType,
+		URLs:           resp.URLs,
+	}
+
+	client.mu.Lock()
+	// Only cache the identity if the session was not replaced by another login meanwhile.
+	if client.session.id == s.id {
+		client.session.identity = identity
+		client.session.user.id = identity.UserID
+		client.session.user.name = identity.Username
+		client.session.user.email = identity.Email
+		client.session.user.fullName = identity.DisplayName
+	}
+	client.mu.Unlock()
+
+	return identity.clone(), nil
+}
+
+// clone returns a copy of the identity which does not share its URLs with the cached one.
+func (identity *Identity) clone() *Identity {
+	out := *identity
+	if identity.URLs != nil {
+		out.URLs = make(map[string]string, len(identity.URLs))
+		for key, value := range identity.URLs {
+			out.URLs[key] = value
+		}
+	}
+	return &out
+}
+
+// firstNonEmpty returns the first of values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
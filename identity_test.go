@@ -0,0 +1,110 @@
+package simpleforce
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_Identity(t *testing.T) {
+	requests := map[string]int{}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		if r.URL.Path != "/services/oauth2/token" && r.Header.Get("Authorization") != "Bearer __SESSION__" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `[{"message":"Session expired or invalid","errorCode":"INVALID_SESSION_ID"}]`)
+			return
+		}
+		switch r.URL.Path {
+		case "/services/oauth2/token":
+			fmt.Fprintf(w, `{"access_token":"__SESSION__","refresh_token":"__REFRESH__","instance_url":"%s","id":"%s/id/00D000000000001/005000000000001"}`,
+				server.URL, server.URL)
+		case "/id/00D000000000001/005000000000001":
+			fmt.Fprintf(w, `{"id":"%s/id/00D000000000001/005000000000001","user_id":"005000000000001","organization_id":"00D000000000001","username":"user@example.com","display_name":"Example User","email":"user@example.com","locale":"en_US","language":"en_US","timezone":"America/Los_Angeles","user_type":"STANDARD","urls":{"rest":"%s/services/data/v{version}/"}}`,
+				server.URL, server.URL)
+		case "/services/oauth2/userinfo":
+			fmt.Fprintf(w, `{"sub":"%s/id/00D000000000001/005000000000002","user_id":"005000000000002","organization_id":"00D000000000001","preferred_username":"other@example.com","name":"Other User","email":"other@example.com","locale":"de_DE","zoneinfo":"Europe/Berlin","urls":{}}`,
+				server.URL)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	if _, err := client.Identity(); err != ErrAuthentication {
+		t.Fail()
+	}
+
+	// OAuth sessions use the identity URL.
+	if err := client.LoginWithAuthCode(server.URL, "__CODE__"); err != nil {
+		t.Fatal(err)
+	}
+	identity, err := client.Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.UserID != "005000000000001" || identity.OrganizationID != "00D000000000001" ||
+		identity.Username != "user@example.com" || identity.DisplayName != "Example User" ||
+		identity.Timezone != "America/Los_Angeles" || identity.URLs["rest"] == "" {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+	if client.currentSession().user.name != "user@example.com" || client.currentSession().user.fullName != "Example User" {
+		t.Fail()
+	}
+
+	// The identity is cached, also when the session is refreshed, and callers cannot change it.
+	identity.URLs["rest"] = "changed"
+	if err = client.refreshSession(context.Background(), client.GetSid()); err != nil {
+		t.Fatal(err)
+	}
+	identity, err = client.Identity()
+	if err != nil || requests["/id/00D000000000001/005000000000001"] != 1 || requests["/services/oauth2/token"] != 2 ||
+		identity.URLs["rest"] == "changed" {
+		t.Errorf("unexpected %+v %v %v", identity, err, requests)
+	}
+
+	// Sessions set directly use the userinfo endpoint.
+	client.SetSidLoc("__SESSION__", server.URL)
+	identity, err = client.Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.UserID != "005000000000002" || identity.Username != "other@example.com" ||
+		identity.DisplayName != "Other User" || identity.Timezone != "Europe/Berlin" ||
+		identity.IdentityURL != server.URL+"/id/00D000000000001/005000000000002" {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+
+	// Negative: invalid session.
+	client.SetSidLoc("__EXPIRED__", server.URL)
+	if _, err = client.Identity(); err == nil {
+		t.Fail()
+	}
+}
+
+func TestClient_IdentitySessionSwitch(t *testing.T) {
+	var client *Client
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Another user signs in on the same instance while the lookup is in flight.
+		client.SetSidLoc("__SECOND__", server.URL)
+		fmt.Fprint(w, `{"user_id":"005000000000001","organization_id":"00D000000000001","preferred_username":"first@example.com"}`)
+	}))
+	defer server.Close()
+
+	client = NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	client.SetSidLoc("__FIRST__", server.URL)
+	identity, err := client.Identity()
+	if err != nil || identity.Username != "first@example.com" {
+		t.Fatalf("unexpected %+v %v", identity, err)
+	}
+
+	// Negative: the identity of the first session is not cached for the second one.
+	if s := client.currentSession(); s.identity != nil || s.user.name != "" {
+		t.Errorf("identity cached for the new session: %+v", s.user)
+	}
+}
@@ -0,0 +1,99 @@
+package simpleforce
+
+import (
+	"context"
+)
+
+// QueryIterator iterates over the records of a query, fetching the pages of the result lazily by following
+// nextRecordsUrl:
+//
+//	it := client.QueryIterator("SELECT Id, Name FROM Account")
+//	for it.Next() {
+//		record := it.Record()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type QueryIterator struct {
+	client    *Client
+	ctx       context.Context
+	next      string // SOQL or nextRecordsUrl of the next page, empty once the last page is fetched.
+	page      []SObject
+	pos       int
+	record    *SObject
+	totalSize int
+	err       error
+}
+
+// QueryIterator returns an iterator over the records of the SOQL query q. No request is sent until Next is called.
+func (client *Client) QueryIterator(q string) *QueryIterator {
+	return client.QueryIteratorContext(context.Background(), q)
+}
+
+// QueryIteratorContext is like QueryIterator, the requests are bound to ctx and the iteration stops once ctx is done.
+func (client *Client) QueryIteratorContext(ctx context.Context, q string) *QueryIterator {
+	return &QueryIterator{client: client, ctx: ctx, next: q}
+}
+
+// Next advances to the next record, fetching the next page if needed. It returns false at the end of the result or
+// on failure, see Err.
+func (it *QueryIterator) Next() bool {
+	it.record = nil
+	if it.err != nil {
+		return false
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return false
+	}
+	for it.pos >= len(it.page) {
+		if it.next == "" {
+			return false
+		}
+		result, err := it.client.QueryContext(it.ctx, it.next)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page, it.pos, it.totalSize = result.Records, 0, result.TotalSize
+		it.next = result.NextRecordsURL
+		if result.Done {
+			it.next = ""
+		}
+	}
+	it.record = &it.page[it.pos]
+	it.pos++
+	return true
+}
+
+// Record returns the current record, nil if Next was not called or returned false.
+func (it *QueryIterator) Record() *SObject {
+	return it.record
+}
+
+// TotalSize returns the number of records matching the query, known once the first page is fetched.
+func (it *QueryIterator) TotalSize() int {
+	return it.totalSize
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *QueryIterator) Err() error {
+	return it.err
+}
+
+// QueryAllPages returns all the records of the SOQL query q, fetching every page of the result.
+func (client *Client) QueryAllPages(q string) ([]SObject, error) {
+	return client.QueryAllPagesContext(context.Background(), q)
+}
+
+// QueryAllPagesContext is like QueryAllPages, the requests are bound to ctx.
+func (client *Client) QueryAllPagesContext(ctx context.Context, q string) ([]SObject, error) {
+	var records []SObject
+	it := client.QueryIteratorContext(ctx, q)
+	for it.Next() {
+		records = append(records, *it.Record())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,64 @@
+package simpleforce
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/veloceapps/simpleforce/simpleforcetest"
+)
+
+func TestClient_QueryIterator(t *testing.T) {
+	server := simpleforcetest.NewServer()
+	defer server.Close()
+	server.SetPageSize(2)
+	for i := 0; i < 5; i++ {
+		server.Insert("Case", map[string]interface{}{"Subject": fmt.Sprintf("Case %d", i)})
+	}
+
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	client.SetSidLoc(server.NewSession(), server.URL)
+
+	// The pages are followed lazily, and the records can be used online.
+	it := client.QueryIterator("SELECT Id, Subject FROM Case ORDER BY Subject")
+	if it.Record() != nil || it.TotalSize() != 0 {
+		t.Fail()
+	}
+	var subjects []string
+	for it.Next() {
+		record := it.Record()
+		subjects = append(subjects, record.StringField("Subject"))
+		if record.client() != client {
+			t.Error("record not bound to the client")
+		}
+	}
+	if it.Err() != nil || it.TotalSize() != 5 || it.Next() || it.Record() != nil {
+		t.Errorf("unexpected end of iteration %v", it.Err())
+	}
+	if fmt.Sprint(subjects) != "[Case 0 Case 1 Case 2 Case 3 Case 4]" {
+		t.Errorf("unexpected subjects %v", subjects)
+	}
+
+	records, err := client.QueryAllPages("SELECT Id FROM Case WHERE Subject != 'Case 0'")
+	if err != nil || len(records) != 4 {
+		t.Errorf("unexpected %d records %v", len(records), err)
+	}
+	records, err = client.QueryAllPages("SELECT Id FROM Case WHERE Subject = 'none'")
+	if err != nil || len(records) != 0 {
+		t.Errorf("unexpected %d records %v", len(records), err)
+	}
+
+	// Negative: the iteration stops when the context is done or a page fails.
+	ctx, cancel := context.WithCancel(context.Background())
+	it = client.QueryIteratorContext(ctx, "SELECT Id FROM Case")
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+	cancel()
+	if it.Next() || it.Err() != context.Canceled {
+		t.Errorf("unexpected %v", it.Err())
+	}
+	if _, err = client.QueryAllPages("SELECT FROM Case"); err == nil {
+		t.Fail()
+	}
+}
@@ -0,0 +1,201 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrAPIUsageThreshold is returned instead of sending a request once the API usage of the org has reached the
+// threshold set with SetAPIUsageThreshold.
+var ErrAPIUsageThreshold = errors.New("api usage threshold reached")
+
+// DefaultAPIUsageProbeInterval is the default interval after which a request is let through the usage threshold to
+// find out whether the usage went down, see SetAPIUsageProbeInterval.
+const DefaultAPIUsageProbeInterval = 5 * time.Minute
+
+// Names of commonly monitored org limits.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_limits.htm
+const (
+	LimitDailyAPIRequests              = "DailyApiRequests"
+	LimitDailyAsyncApexExecutions      = "DailyAsyncApexExecutions"
+	LimitDailyBulkAPIBatches           = "DailyBulkApiBatches"
+	LimitDailyBulkV2QueryJobs          = "DailyBulkV2QueryJobs"
+	LimitDailyStreamingAPIEvents       = "DailyStreamingApiEvents"
+	LimitDataStorageMB                 = "DataStorageMB"
+	LimitFileStorageMB                 = "FileStorageMB"
+	LimitHourlyPublishedPlatformEvents = "HourlyPublishedPlatformEvents"
+	LimitSingleEmail                   = "SingleEmail"
+	LimitMassEmail                     = "MassEmail"
+)
+
+// Limit is the maximum and the remaining amount of an org limit.
+type Limit struct {
+	Max       int `json:"Max"`
+	Remaining int `json:"Remaining"`
+}
+
+// Used returns the consumed amount of the limit.
+func (limit Limit) Used() int {
+	return limit.Max - limit.Remaining
+}
+
+// Limits maps the names of org limits, e.g. LimitDailyAPIRequests, to their current values.
+type Limits map[string]Limit
+
+// APIUsage is the API request usage of the org, as reported by Salesforce with every REST response.
+type APIUsage struct {
+	Used      int
+	Limit     int
+	UpdatedAt time.Time
+}
+
+// apiUsageTracker records the latest API usage and guards requests against the usage threshold.
+type apiUsageTracker struct {
+	mu            sync.Mutex
+	usage         APIUsage
+	threshold     float64
+	probeInterval time.Duration
+	probedAt      time.Time // when a request was last let through to refresh a usage above the threshold.
+}
+
+// unguardedKey is the context key marking requests which are sent whatever the API usage, such as the limits request
+// refreshing it.
+type unguardedKey struct{}
+
+// Limits returns the org limits from the limits resource.
+func (client *Client) Limits() (Limits, error) {
+	return client.LimitsContext(context.Background())
+}
+
+// LimitsContext is like Limits, the request is bound to ctx. It is sent even if the API usage threshold is reached,
+// so that the usage can be refreshed.
+func (client *Client) LimitsContext(ctx context.Context) (Limits, error) {
+	if !client.isLoggedIn() {
+		return nil, ErrAuthentication
+	}
+
+	ctx = context.WithValue(ctx, unguardedKey{}, true)
+	data, err := client.httpRequest(ctx, http.MethodGet, client.makeURL("limits/"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var limits Limits
+	err = json.Unmarshal(data, &limits)
+	if err != nil {
+		return nil, err
+	}
+	if limit, ok := limits[LimitDailyAPIRequests]; ok {
+		client.usage.record(APIUsage{Used: limit.Used(), Limit: limit.Max, UpdatedAt: time.Now()})
+	}
+	return limits, nil
+}
+
+// APIUsage returns the API request usage of the org reported with the latest response, and false if none was
+// reported yet.
+func (client *Client) APIUsage() (APIUsage, bool) {
+	if client.usage == nil {
+		return APIUsage{}, false
+	}
+	client.usage.mu.Lock()
+	defer client.usage.mu.Unlock()
+	return client.usage.usage, !client.usage.usage.UpdatedAt.IsZero()
+}
+
+// SetAPIUsageThreshold makes the client refuse new requests with ErrAPIUsageThreshold once the reported API usage
+// reaches fraction, between 0 and 1, of the daily limit of the org. Logins, logouts and Limits are always sent, and a
+// request is let through once in a while to notice when the usage went down, see SetAPIUsageProbeInterval. A fraction
+// of 0, the default, disables the guard.
+func (client *Client) SetAPIUsageThreshold(fraction float64) {
+	client.usage.mu.Lock()
+	defer client.usage.mu.Unlock()
+	client.usage.threshold = fraction
+}
+
+// SetAPIUsageProbeInterval sets how long requests are refused once the API usage threshold is reached before one of
+// them is sent again to refresh the usage, e.g. after the daily allowance was reset. The interval starts with the
+// last usage reported; DefaultAPIUsageProbeInterval is used if interval is 0.
+func (client *Client) SetAPIUsageProbeInterval(interval time.Duration) {
+	client.usage.mu.Lock()
+	defer client.usage.mu.Unlock()
+	client.usage.probeInterval = interval
+}
+
+// check returns ErrAPIUsageThreshold if the usage has reached the threshold, unless the request bound to ctx is not
+// guarded or the usage is stale, in which case the request is let through to refresh it.
+func (tracker *apiUsageTracker) check(ctx context.Context) error {
+	if tracker == nil {
+		return nil
+	}
+	if unguarded, _ := ctx.Value(unguardedKey{}).(bool); unguarded {
+		return nil
+	}
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	usage := tracker.usage
+	if tracker.threshold <= 0 || usage.Limit <= 0 || float64(usage.Used) < tracker.threshold*float64(usage.Limit) {
+		return nil
+	}
+
+	interval := tracker.probeInterval
+	if interval <= 0 {
+		interval = DefaultAPIUsageProbeInterval
+	}
+	checked := usage.UpdatedAt
+	if tracker.probedAt.After(checked) {
+		checked = tracker.probedAt
+	}
+	if time.Since(checked) >= interval {
+		// Only one request probes the usage per interval.
+		tracker.probedAt = time.Now()
+		return nil
+	}
+	return fmt.Errorf("%w: %d of %d requests used", ErrAPIUsageThreshold, usage.Used, usage.Limit)
+}
+
+// record stores usage as the latest usage.
+func (tracker *apiUsageTracker) record(usage APIUsage) {
+	if tracker == nil {
+		return
+	}
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.usage = usage
+}
+
+// reset forgets the usage, e.g. when the client logs out of the org.
+func (tracker *apiUsageTracker) reset() {
+	tracker.record(APIUsage{})
+}
+
+// parseLimitInfo extracts the API usage from a Sforce-Limit-Info header such as "api-usage=25/15000".
+func parseLimitInfo(header string) (APIUsage, bool) {
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if !strings.HasPrefix(part, "api-usage=") {
+			continue
+		}
+		values := strings.SplitN(strings.TrimPrefix(part, "api-usage="), "/", 2)
+		if len(values) != 2 {
+			return APIUsage{}, false
+		}
+		used, err := strconv.Atoi(values[0])
+		if err != nil {
+			return APIUsage{}, false
+		}
+		limit, err := strconv.Atoi(values[1])
+		if err != nil {
+			return APIUsage{}, false
+		}
+		return APIUsage{Used: used, Limit: limit, UpdatedAt: time.Now()}, true
+	}
+	return APIUsage{}, false
+}
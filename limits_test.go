@@ -0,0 +1,166 @@
+package simpleforce
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseLimitInfo(t *testing.T) {
+	usage, ok := parseLimitInfo("api-usage=25/15000")
+	if !ok || usage.Used != 25 || usage.Limit != 15000 || usage.UpdatedAt.IsZero() {
+		t.Fail()
+	}
+	usage, ok = parseLimitInfo("per-app-api-usage=17/250(appName=sample-app), api-usage=40/5000")
+	if !ok || usage.Used != 40 || usage.Limit != 5000 {
+		t.Fail()
+	}
+	for _, header := range []string{"", "api-usage=", "api-usage=x/100", "api-usage=25/"} {
+		if _, ok = parseLimitInfo(header); ok {
+			t.Errorf("%q parsed", header)
+		}
+	}
+}
+
+func TestClient_Limits(t *testing.T) {
+	used := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		used++
+		w.Header().Set("Sforce-Limit-Info", fmt.Sprintf("api-usage=%d/10", used))
+		switch r.URL.Path {
+		case "/services/data/v" + DefaultAPIVersion + "/limits/":
+			fmt.Fprintf(w, `{"DailyApiRequests":{"Max":10,"Remaining":%d,"Ant Migration Tool":{"Max":0,"Remaining":0}},"DataStorageMB":{"Max":5,"Remaining":3}}`,
+				10-used)
+		default:
+			fmt.Fprint(w, `{"totalSize":0,"done":true,"records":[]}`)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	if _, ok := client.APIUsage(); ok {
+		t.Fail()
+	}
+	if _, err := client.Limits(); err != ErrAuthentication {
+		t.Fail()
+	}
+	client.SetSidLoc("__SESSION__", server.URL)
+
+	limits, err := client.Limits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits[LimitDailyAPIRequests].Used() != 1 || limits[LimitDataStorageMB].Remaining != 3 {
+		t.Errorf("unexpected limits %+v", limits)
+	}
+
+	// Every response updates the usage.
+	if _, err = client.Query("SELECT Id FROM Case"); err != nil {
+		t.Fatal(err)
+	}
+	if usage, ok := client.APIUsage(); !ok || usage.Used != 2 || usage.Limit != 10 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+
+	// The guard refuses requests once the threshold is reached.
+	client.SetAPIUsageThreshold(0.3)
+	if _, err = client.Query("SELECT Id FROM Case"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Query("SELECT Id FROM Case"); !errors.Is(err, ErrAPIUsageThreshold) || used != 3 {
+		t.Errorf("unexpected %v after %d requests", err, used)
+	}
+	if _, err = client.DescribeGlobal(); !errors.Is(err, ErrAPIUsageThreshold) {
+		t.Fail()
+	}
+
+	// Negative: the usage of another org does not apply.
+	client.SetSidLoc("__SESSION__", server.URL)
+	if _, ok := client.APIUsage(); ok {
+		t.Fail()
+	}
+	if _, err = client.Query("SELECT Id FROM Case"); err != nil {
+		t.Fail()
+	}
+}
+
+func TestClient_APIUsageRecovery(t *testing.T) {
+	var mu sync.Mutex
+	used := 9
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Sforce-Limit-Info", fmt.Sprintf("api-usage=%d/10", used))
+		switch r.URL.Path {
+		case "/services/data/v" + DefaultAPIVersion + "/limits/":
+			fmt.Fprintf(w, `{"DailyApiRequests":{"Max":10,"Remaining":%d}}`, 10-used)
+		default:
+			fmt.Fprint(w, `{"totalSize":0,"done":true,"records":[]}`)
+		}
+	}))
+	defer server.Close()
+	setUsed := func(n int) {
+		mu.Lock()
+		defer mu.Unlock()
+		used = n
+	}
+
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	client.SetSidLoc("__SESSION__", server.URL)
+	client.SetAPIUsageThreshold(0.8)
+	client.SetAPIUsageProbeInterval(time.Hour)
+
+	// The guard trips.
+	if _, err := client.Query("SELECT Id FROM Case"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Query("SELECT Id FROM Case"); !errors.Is(err, ErrAPIUsageThreshold) {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	// Limits is still sent and refreshes the usage once the daily allowance is reset.
+	setUsed(1)
+	limits, err := client.Limits()
+	if err != nil || limits[LimitDailyAPIRequests].Used() != 1 {
+		t.Fatalf("unexpected %+v %v", limits, err)
+	}
+	if _, err = client.Query("SELECT Id FROM Case"); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+
+	// Once the usage is stale, a single request is let through to refresh it.
+	setUsed(9)
+	client.SetAPIUsageProbeInterval(50 * time.Millisecond)
+	if _, err = client.Query("SELECT Id FROM Case"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Query("SELECT Id FROM Case"); !errors.Is(err, ErrAPIUsageThreshold) {
+		t.Fatalf("unexpected %v", err)
+	}
+	setUsed(2)
+	time.Sleep(60 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if _, err = client.Query("SELECT Id FROM Case"); err != nil {
+			t.Errorf("unexpected %v", err)
+		}
+	}
+	if usage, _ := client.APIUsage(); usage.Used != 2 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+
+	// Negative: the probe does not lift the guard while the usage stays above the threshold.
+	setUsed(9)
+	for _, wait := range []time.Duration{0, 60 * time.Millisecond} {
+		time.Sleep(wait)
+		if _, err = client.Query("SELECT Id FROM Case"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = client.Query("SELECT Id FROM Case"); !errors.Is(err, ErrAPIUsageThreshold) {
+			t.Errorf("unexpected %v", err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package simpleforce
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// Level is the severity of a log message.
+type Level int
+
+// Log levels, from the most to the least verbose.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (level Level) String() string {
+	switch level {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", int(level))
+	}
+}
+
+// Logger receives the log messages of a Client. keyvals holds alternating keys and values describing the message,
+// e.g. "status", 401. Session IDs, tokens and passwords are redacted before they reach the Logger.
+type Logger interface {
+	Log(level Level, msg string, keyvals ...interface{})
+}
+
+// LoggerFunc adapts an ordinary function to the Logger interface.
+type LoggerFunc func(level Level, msg string, keyvals ...interface{})
+
+// Log calls f.
+func (f LoggerFunc) Log(level Level, msg string, keyvals ...interface{}) {
+	f(level, msg, keyvals...)
+}
+
+// nopLogger discards all messages. It is the default of a Client.
+type nopLogger struct{}
+
+func (nopLogger) Log(Level, string, ...interface{}) {}
+
+// stdLogger writes messages at or above a minimum level as lines of text.
+type stdLogger struct {
+	logger   *log.Logger
+	minLevel Level
+}
+
+// NewStdLogger returns a Logger writing messages at minLevel or above to logger as lines of text, e.g.
+// "[simpleforce] warn: request failed, retrying delay=1s". The standard logger is used if logger is nil.
+func NewStdLogger(logger *log.Logger, minLevel Level) Logger {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &stdLogger{logger: logger, minLevel: minLevel}
+}
+
+func (l *stdLogger) Log(level Level, msg string, keyvals ...interface{}) {
+	if level < l.minLevel {
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s: %s", logPrefix, level, msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		var value interface{} = "(missing)"
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		fmt.Fprintf(&b, " %v=%v", keyvals[i], value)
+	}
+	l.logger.Println(b.String())
+}
+
+// SetLogger sets the Logger receiving the log messages of the client. Nothing is logged if logger is nil, which is
+// the default.
+func (client *Client) SetLogger(logger Logger) {
+	if logger == nil {
+		logger = nopLogger{}
+	}
+	client.logger = logger
+}
+
+// log sends a message to the logger of the client, redacting secrets.
+func (client *Client) log(level Level, msg string, keyvals ...interface{}) {
+	if client == nil || client.clientState == nil || client.logger == nil {
+		return
+	}
+	if _, ok := client.logger.(nopLogger); ok {
+		return
+	}
+	client.logger.Log(level, redactString(msg), redactKeyvals(keyvals)...)
+}
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are the keys, compared case-insensitively and ignoring separators, whose values are never logged.
+var sensitiveKeys = map[string]bool{
+	"sid":           true,
+	"session":       true,
+	"sessionid":     true,
+	"password":      true,
+	"token":         true,
+	"securitytoken": true,
+	"accesstoken":   true,
+	"refreshtoken":  true,
+	"authorization": true,
+	"clientsecret":  true,
+	"secret":        true,
+	"code":          true,
+	"codeverifier":  true,
+}
+
+// sensitivePatterns match secrets embedded in messages, URLs and response bodies. The first group is kept.
+var sensitivePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(<(?:\w+:)?(?:sessionId|password)>)[^<]*`),
+	regexp.MustCompile(`("(?:access_token|refresh_token|client_secret|password|sessionId)"\s*:\s*")[^"]*`),
+	regexp.MustCompile(`((?:access_token|refresh_token|client_secret|password|code|code_verifier|sid)=)[^&\s]*`),
+	regexp.MustCompile(`((?i:bearer)\s+)\S+`),
+	regexp.MustCompile(`()\b00D\w{12,15}![\w.]+`),
+}
+
+// redactKeyvals returns a copy of keyvals without the values of sensitive keys and secrets embedded in values.
+func redactKeyvals(keyvals []interface{}) []interface{} {
+	out := make([]interface{}, len(keyvals))
+	for i, value := range keyvals {
+		if i%2 == 1 && isSensitiveKey(fmt.Sprint(keyvals[i-1])) {
+			out[i] = redacted
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			out[i] = redactString(v)
+		case []byte:
+			out[i] = redactString(string(v))
+		case error:
+			out[i] = redactString(v.Error())
+		default:
+			out[i] = v
+		}
+	}
+	return out
+}
+
+// isSensitiveKey returns if the values of key, a log key or a header name, must never be logged.
+func isSensitiveKey(key string) bool {
+	return sensitiveKeys[strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))]
+}
+
+// redactString replaces session IDs, tokens and passwords found in s.
+func redactString(s string) string {
+	for _, pattern := range sensitivePatterns {
+		s = pattern.ReplaceAllString(s, "${1}"+redacted)
+	}
+	return s
+}
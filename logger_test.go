@@ -0,0 +1,99 @@
+package simpleforce
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type logEntry struct {
+	level   Level
+	msg     string
+	keyvals []interface{}
+}
+
+func recordLogger(entries *[]logEntry) Logger {
+	return LoggerFunc(func(level Level, msg string, keyvals ...interface{}) {
+		*entries = append(*entries, logEntry{level, msg, keyvals})
+	})
+}
+
+func TestRedact(t *testing.T) {
+	cases := map[string]string{
+		`<urn:sessionId>00D5g000004abcd!AQ0AQ.xyz</urn:sessionId>`:        `<urn:sessionId>[REDACTED]</urn:sessionId>`,
+		`<n1:password>hunter2TOKEN</n1:password>`:                         `<n1:password>[REDACTED]</n1:password>`,
+		`{"access_token":"abc","instance_url":"https://x"}`:               `{"access_token":"[REDACTED]","instance_url":"https://x"}`,
+		`grant_type=refresh_token&refresh_token=5Aep861&client_id=id`:     `grant_type=refresh_token&refresh_token=[REDACTED]&client_id=id`,
+		`Authorization: Bearer 00D5g000004abcd!AQ0AQ.xyz`:                 `Authorization: Bearer [REDACTED]`,
+		`session 00D5g000004abcdEAA!AQ0AQ.xyz expired`:                    `session [REDACTED] expired`,
+		`https://example.my.salesforce.com/secur/frontdoor.jsp?sid=abc&x`: `https://example.my.salesforce.com/secur/frontdoor.jsp?sid=[REDACTED]&x`,
+		`nothing to hide`: `nothing to hide`,
+	}
+	for in, expected := range cases {
+		if out := redactString(in); out != expected {
+			t.Errorf("redactString(%q) = %q, expected %q", in, out, expected)
+		}
+	}
+
+	keyvals := redactKeyvals([]interface{}{
+		"session_id", "abc", "Password", "hunter2", "status", 401,
+		"body", []byte(`{"refresh_token":"xyz"}`), "error", errors.New("Bearer abc rejected"),
+	})
+	expected := []interface{}{
+		"session_id", redacted, "Password", redacted, "status", 401,
+		"body", `{"refresh_token":"[REDACTED]"}`, "error", "Bearer [REDACTED] rejected",
+	}
+	if fmt.Sprint(keyvals) != fmt.Sprint(expected) {
+		t.Errorf("unexpected %v", keyvals)
+	}
+}
+
+func TestNewStdLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := NewStdLogger(log.New(buf, "", 0), LevelInfo)
+	logger.Log(LevelDebug, "hidden")
+	logger.Log(LevelWarn, "request failed, retrying", "status", 503, "odd")
+	if buf.String() != "[simpleforce] warn: request failed, retrying status=503 odd=(missing)\n" {
+		t.Errorf("unexpected %q", buf.String())
+	}
+}
+
+func TestClient_SetLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `[{"message":"bad request from 00D5g000004abcd!AQ0AQ.xyz","errorCode":"MALFORMED_QUERY"}]`)
+	}))
+	defer server.Close()
+
+	// Nothing is logged by default, or when a nil logger is set.
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	client.SetSidLoc("00D5g000004abcd!AQ0AQ.xyz", server.URL)
+	client.SetLogger(nil)
+	if _, err := client.Query("SELECT Id FROM Case"); err == nil {
+		t.Fail()
+	}
+	(&Client{}).log(LevelError, "zero value client")
+
+	var entries []logEntry
+	client.SetLogger(recordLogger(&entries))
+	if _, err := client.QueryContext(context.Background(), "SELECT Id FROM Case"); err == nil {
+		t.Fail()
+	}
+	levels := map[Level]bool{}
+	for _, entry := range entries {
+		levels[entry.level] = true
+		line := fmt.Sprint(entry.msg, entry.keyvals)
+		if strings.Contains(line, "AQ0AQ.xyz") {
+			t.Errorf("session leaked in %q", line)
+		}
+	}
+	if !levels[LevelError] || !levels[LevelDebug] {
+		t.Errorf("unexpected entries %v", entries)
+	}
+}
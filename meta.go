@@ -2,12 +2,12 @@ package simpleforce
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/pkg/errors"
 	"io"
 	"io/ioutil"
-	"log"
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
@@ -38,12 +38,23 @@ type MetaDeployResponse struct {
 }
 
 
+// MetaDeploy deploys a zipped metadata package with the Metadata REST API and waits for the deployment to complete.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_meta.meta/api_meta/meta_rest_deploy.htm
 func (c *Client) MetaDeploy(zip []byte, testLevel string) (*MetaDeployResult, error) {
+	return c.MetaDeployContext(context.Background(), zip, testLevel)
+}
+
+// MetaDeployContext is like MetaDeploy. The deploy request and the polling for its result stop when ctx is done.
+func (c *Client) MetaDeployContext(ctx context.Context, zip []byte, testLevel string) (*MetaDeployResult, error) {
 	if !c.isLoggedIn() {
 		return nil, ErrAuthentication
 	}
+	if err := c.usage.check(ctx); err != nil {
+		return nil, err
+	}
 
-	url := fmt.Sprintf("%s/services/data/v%s/metadata/deployRequest", c.instanceURL, DefaultAPIVersion)
+	s := c.currentSession()
+	url := fmt.Sprintf("%s/services/data/v%s/metadata/deployRequest", s.instanceURL, c.version())
 	// Prepare a form that you will submit to that URL.
 	var b bytes.Buffer
 	w := multipart.NewWriter(&b)
@@ -99,28 +110,31 @@ func (c *Client) MetaDeploy(zip []byte, testLevel string) (*MetaDeployResult, er
 	w.Close()
 
 	// Now that you have a form, you can submit it to your handler.
-	req, err := http.NewRequest("POST", url, &b)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, &b)
 	if err != nil {
 		return &MetaDeployResult{Success: false}, err
 	}
 	// Don't forget to set the content type, this will contain the boundary.
 	req.Header.Set("Content-Type", w.FormDataContentType())
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.sessionID))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.id))
 
 	// Submit the request
-	res, err := c.httpClient.Do(req)
+	res, err := c.do(req)
+	if err != nil {
+		return &MetaDeployResult{Success: false}, err
+	}
+	resBytes, err := ioutil.ReadAll(res.Body)
+	// The open body holds a slot of SetMaxConcurrentRequests, which the polling requests below need.
+	res.Body.Close()
 	if err != nil {
 		return &MetaDeployResult{Success: false}, err
 	}
-	defer res.Body.Close()
 
 	// Check the response
 	if res.StatusCode != http.StatusCreated {
-		resBytes, _ := ioutil.ReadAll(res.Body)
-		log.Printf("Error from server: %s", string(resBytes))
-		return &MetaDeployResult{Success: false}, fmt.Errorf("bad status: %s", res.Status)
+		c.log(LevelError, "deploy request failed", "status", res.StatusCode, "body", resBytes)
+		return &MetaDeployResult{Success: false}, ParseSalesforceError(res.StatusCode, resBytes)
 	}
-	resBytes, _ := ioutil.ReadAll(res.Body)
 
 	var mr MetaDeployResponse
 	err = json.Unmarshal(resBytes, &mr)
@@ -139,9 +153,9 @@ func (c *Client) MetaDeploy(zip []byte, testLevel string) (*MetaDeployResult, er
 			mr.DeployResult.ErrorMessage = "Timeout while waiting for result"
 			break;
 		}
-		url = fmt.Sprintf("%s/services/data/v%s/metadata/deployRequest/%s?includeDetails=true", c.instanceURL, DefaultAPIVersion, mr.ID)
+		url = fmt.Sprintf("%s/services/data/v%s/metadata/deployRequest/%s?includeDetails=true", s.instanceURL, c.version(), mr.ID)
 		//req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.sessionID))
-		resBytes, err = c.httpRequest("GET", url, nil)
+		resBytes, err = c.httpRequest(ctx, "GET", url, nil)
 		if err != nil {
 			return &MetaDeployResult{Success: false}, err
 		}
@@ -152,7 +166,11 @@ func (c *Client) MetaDeploy(zip []byte, testLevel string) (*MetaDeployResult, er
 		if mr.DeployResult.Done {
 			break
 		}
-		time.Sleep(1*time.Second)
+		select {
+		case <-ctx.Done():
+			return &MetaDeployResult{Success: false}, ctx.Err()
+		case <-time.After(1*time.Second):
+		}
 	}
 
 	if !mr.DeployResult.Success {
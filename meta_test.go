@@ -3,9 +3,14 @@ package simpleforce
 import (
 	"archive/zip"
 	"bytes"
+	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 const SecuritySettingsMeta = `<?xml version="1.0" encoding="UTF-8"?>
@@ -1411,3 +1416,62 @@ func TestClient_MetaDeploy(t *testing.T) {
 	fmt.Printf("Result: %+v\n", result)
 }
 
+
+func TestClient_MetaDeployContext(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The API version of the client is used.
+		if !strings.HasPrefix(r.URL.Path, "/services/data/v58.0/metadata/deployRequest") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id":"0Af000000000001","deployResult":{"id":"0Af000000000001","done":false,"status":"Pending"}}`)
+		default:
+			polls++
+			fmt.Fprint(w, `{"id":"0Af000000000001","deployResult":{"id":"0Af000000000001","done":false,"status":"InProgress"}}`)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, DefaultClientID, "58.0")
+	client.SetSidLoc("__SESSION__", server.URL)
+
+	// Polling stops once the context is done instead of waiting for the deployment.
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	result, err := client.MetaDeployContext(ctx, []byte("zip"), "NoTestRun")
+	if err != context.DeadlineExceeded || result.Success || polls == 0 {
+		t.Fail()
+	}
+	if time.Since(started) > 5*time.Second {
+		t.Fail()
+	}
+}
+
+func TestClient_MetaDeployMaxConcurrentRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id":"0Af000000000001","deployResult":{"id":"0Af000000000001","done":false,"status":"Pending"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":"0Af000000000001","deployResult":{"id":"0Af000000000001","done":true,"success":true,"status":"Succeeded"}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	client.SetSidLoc("__SESSION__", server.URL)
+	client.SetMaxConcurrentRequests(1)
+
+	// The deploy request does not keep its slot while the deployment is polled.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := client.MetaDeployContext(ctx, []byte("zip"), "NoTestRun")
+	if err != nil || !result.Success {
+		t.Errorf("unexpected %+v %v", result, err)
+	}
+}
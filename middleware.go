@@ -0,0 +1,101 @@
+package simpleforce
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// RoundTripFunc sends a request to Salesforce and returns its response.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps the round trip of every request sent by a Client: API calls, logins, logouts, metadata deploys
+// and file downloads. A Middleware may inspect or modify the request, call next, and inspect the response. It must
+// leave the response body readable for the client.
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// Use appends middleware to the chain of the client. The middleware added first sees a request first and its
+// response last.
+func (client *Client) Use(middleware ...Middleware) {
+	client.middleware = append(client.middleware, middleware...)
+}
+
+// do adds the headers of the client and of the context to req, waits for the rate limiter, and sends it through the
+// middleware chain and the HTTP client. The middleware only sees the round trip, not the time waited for a slot.
+func (client *Client) do(req *http.Request) (*http.Response, error) {
+	client.applyHeaders(req)
+	roundTrip := func(req *http.Request) (*http.Response, error) {
+		resp, err := client.httpClient.Do(req)
+		if err == nil {
+			err = decompress(resp)
+		}
+		return resp, err
+	}
+	for i := len(client.middleware) - 1; i >= 0; i-- {
+		roundTrip = client.middleware[i](roundTrip)
+	}
+	resp, err := client.throttle(req, roundTrip)
+	if err == nil {
+		if usage, ok := parseLimitInfo(resp.Header.Get("Sforce-Limit-Info")); ok {
+			client.usage.record(usage)
+		}
+	}
+	return resp, err
+}
+
+// RequestInfo describes a request sent to Salesforce and its outcome.
+type RequestInfo struct {
+	Method         string
+	URL            string
+	RequestHeader  http.Header // copy of the request headers, with the Authorization header redacted.
+	StatusCode     int         // zero if no response was received.
+	ResponseHeader http.Header
+	ErrorCode      string        // Salesforce error code of a failed response, if any.
+	Duration       time.Duration // time of the round trip, excluding the wait for the rate limit and concurrency cap.
+	Err            error         // error of the HTTP client, if any.
+}
+
+// Observe returns a Middleware calling fn with the description of every request once its response is received, e.g.
+// to record metrics or audit logs. The response headers passed to fn must not be modified.
+func Observe(fn func(info *RequestInfo)) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			started := time.Now()
+			resp, err := next(req)
+			info := &RequestInfo{
+				Method:        req.Method,
+				URL:           req.URL.String(),
+				RequestHeader: redactHeader(req.Header),
+				Duration:      time.Since(started),
+				Err:           err,
+			}
+			if resp != nil {
+				info.StatusCode = resp.StatusCode
+				info.ResponseHeader = resp.Header
+				if resp.StatusCode >= 400 {
+					// Buffer the body of failures to extract the error code, and hand a copy on to the client.
+					body, readErr := ioutil.ReadAll(resp.Body)
+					resp.Body.Close()
+					resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+					if readErr == nil {
+						info.ErrorCode = parseAPIError(resp.StatusCode, body).Code()
+					}
+				}
+			}
+			fn(info)
+			return resp, err
+		}
+	}
+}
+
+// redactHeader returns a copy of header without the values of sensitive headers such as Authorization.
+func redactHeader(header http.Header) http.Header {
+	out := header.Clone()
+	for key := range out {
+		if isSensitiveKey(key) {
+			out[key] = []string{redacted}
+		}
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package simpleforce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClient_Use(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Trace-Id") != "__TRACE__" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/services/Soap/u/" + DefaultAPIVersion:
+			fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body><loginResponse><result><serverUrl>%s/services/Soap/u/%s</serverUrl><sessionId>__SESSION__</sessionId><userId>005000000000001</userId><userInfo><userName>user@example.com</userName></userInfo></result></loginResponse></soapenv:Body></soapenv:Envelope>`,
+				"http://"+r.Host, DefaultAPIVersion)
+		case "/services/data/v" + DefaultAPIVersion + "/sobjects/ContentVersion/068000000000001/VersionData":
+			fmt.Fprint(w, "file content")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `[{"errorCode":"NOT_FOUND","message":"The requested resource does not exist"}]`)
+		}
+	}))
+	defer server.Close()
+
+	var order []string
+	var infos []*RequestInfo
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	client.Use(func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			order = append(order, "outer")
+			req.Header.Set("X-Trace-Id", "__TRACE__")
+			return next(req)
+		}
+	}, func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			order = append(order, "inner")
+			return next(req)
+		}
+	})
+	client.Use(Observe(func(info *RequestInfo) {
+		infos = append(infos, info)
+	}))
+
+	if err := client.LoginPassword("user@example.com", "password", "token"); err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 2 || order[0] != "outer" || order[1] != "inner" {
+		t.Errorf("unexpected order %v", order)
+	}
+	if len(infos) != 1 || infos[0].Method != http.MethodPost || infos[0].StatusCode != http.StatusOK ||
+		infos[0].RequestHeader.Get("SOAPAction") != "login" {
+		t.Fatalf("unexpected info %+v", infos)
+	}
+
+	file := filepath.Join(t.TempDir(), "file")
+	if err := client.DownloadFile("068000000000001", file); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(file); string(data) != "file content" {
+		t.Fail()
+	}
+
+	// The body of failures is still readable by the client after being observed.
+	_, err := client.SObject("Case").GetContext(context.Background(), "500000000000001")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("unexpected error %v", err)
+	}
+	last := infos[len(infos)-1]
+	if last.StatusCode != http.StatusNotFound || last.ErrorCode != "NOT_FOUND" || last.Err != nil || last.Duration <= 0 {
+		t.Errorf("unexpected info %+v", last)
+	}
+
+	// The session ID is not observed.
+	if auth := last.RequestHeader.Get("Authorization"); auth != redacted {
+		t.Errorf("unexpected Authorization header %q", auth)
+	}
+
+	// The duration excludes the wait for the rate limiter.
+	client.SetRateLimit(2, 1)
+	client.SObject("Case").GetContext(context.Background(), "500000000000001")
+	started := time.Now()
+	client.SObject("Case").GetContext(context.Background(), "500000000000001")
+	if waited, last := time.Since(started), infos[len(infos)-1]; waited < 300*time.Millisecond || last.Duration > waited/2 {
+		t.Errorf("unexpected duration %v after waiting %v", last.Duration, waited)
+	}
+	client.SetRateLimit(0, 0)
+
+	// Negative: failures of the HTTP client are observed as well.
+	server.Close()
+	if _, err = client.DescribeGlobal(); err == nil || infos[len(infos)-1].Err == nil {
+		t.Fail()
+	}
+}
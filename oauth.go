@@ -0,0 +1,423 @@
+package simpleforce
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// jwtExpiry is the lifetime of the assertion sent to the token endpoint. Salesforce rejects assertions that
+	// expire more than 3 minutes in the future.
+	jwtExpiry = 3 * time.Minute
+
+	// deviceDefaultInterval is the polling interval of the device flow, in seconds, if the server does not specify one.
+	deviceDefaultInterval = 5
+)
+
+// deviceIntervalUnit is the unit of the polling intervals of the device flow, shortened by tests.
+var deviceIntervalUnit = time.Second
+
+// LoginJWT signs into salesforce with the OAuth 2.0 JWT bearer flow. The clientID of the client must be the consumer
+// key of a connected app that has the certificate matching key uploaded, and username must be pre-authorized for it.
+// No user interaction is needed, which makes this flow suitable for CI and backend services.
+// Ref: https://help.salesforce.com/s/articleView?id=sf.remoteaccess_oauth_jwt_flow.htm
+func (client *Client) LoginJWT(username string, key *rsa.PrivateKey) error {
+	return client.LoginJWTContext(context.Background(), username, key)
+}
+
+// LoginJWTContext is like LoginJWT, the token request is bound to ctx.
+func (client *Client) LoginJWTContext(ctx context.Context, username string, key *rsa.PrivateKey) error {
+	if key == nil {
+		return errors.New("private key is required")
+	}
+
+	assertion, err := signJWT(client.clientID, username, client.baseURL, key)
+	if err != nil {
+		client.log(LevelError, "error occurred signing assertion", "error", err)
+		return err
+	}
+
+	data := url.Values{}
+	data.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	data.Set("assertion", assertion)
+
+	token, err := client.requestToken(ctx, client.baseURL, data)
+	if err != nil {
+		return err
+	}
+	err = client.applyToken(ctx, token, client.baseURL, "", username)
+	if err != nil {
+		return err
+	}
+
+	client.log(LevelInfo, "user authenticated", "user", username)
+	return nil
+}
+
+// LoginClientCredentials signs into salesforce with the OAuth 2.0 client credentials flow, using the clientID of the
+// client and the consumer secret of the connected app set with SetClientSecret. The session runs as the integration
+// user configured for the connected app. The client must be created with the My Domain URL of the org as the flow is
+// not supported on login.salesforce.com.
+// Ref: https://help.salesforce.com/s/articleView?id=sf.remoteaccess_oauth_client_credentials_flow.htm
+func (client *Client) LoginClientCredentials() error {
+	return client.LoginClientCredentialsContext(context.Background())
+}
+
+// LoginClientCredentialsContext is like LoginClientCredentials, the token request is bound to ctx.
+func (client *Client) LoginClientCredentialsContext(ctx context.Context) error {
+	if client.clientSecret == "" {
+		return errors.New("client secret is required, see SetClientSecret")
+	}
+
+	data := url.Values{}
+	data.Set("grant_type", "client_credentials")
+	data.Set("client_id", client.clientID)
+	data.Set("client_secret", client.clientSecret)
+
+	token, err := client.requestToken(ctx, client.baseURL, data)
+	if err != nil {
+		return err
+	}
+	return client.applyToken(ctx, token, client.baseURL, client.clientID, "")
+}
+
+// DeviceAuthorization is returned by the first step of the device flow. The user has to visit VerificationURI and
+// enter UserCode to approve the login.
+type DeviceAuthorization struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	Interval        int    `json:"interval"`
+}
+
+// LoginDevice signs into salesforce with the OAuth 2.0 device flow, which suits CLI tools on machines without a
+// browser. prompt is called with the code the user has to enter on another device, then the token endpoint is polled
+// until the user approves or denies access, the code expires, or ctx is done.
+// Ref: https://help.salesforce.com/s/articleView?id=sf.remoteaccess_oauth_device_flow.htm
+func (client *Client) LoginDevice(ctx context.Context, scope string, prompt func(*DeviceAuthorization) error) error {
+	data := url.Values{}
+	data.Set("response_type", "device_code")
+	data.Set("client_id", client.clientID)
+	if scope != "" {
+		data.Set("scope", scope)
+	}
+
+	var authorization DeviceAuthorization
+	err := client.postOAuth(ctx, client.baseURL, data, &authorization)
+	if err != nil {
+		return err
+	}
+	err = prompt(&authorization)
+	if err != nil {
+		return err
+	}
+
+	interval := time.Duration(authorization.Interval) * deviceIntervalUnit
+	if interval <= 0 {
+		interval = deviceDefaultInterval * deviceIntervalUnit
+	}
+	data = url.Values{}
+	data.Set("grant_type", "device")
+	data.Set("client_id", client.clientID)
+	data.Set("code", authorization.DeviceCode)
+	client.addClientSecret(data, client.clientID)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		token, err := client.requestToken(ctx, client.baseURL, data)
+		if err == nil {
+			return client.applyToken(ctx, token, client.baseURL, client.clientID, "")
+		}
+		oauthError, ok := err.(*OAuthError)
+		if !ok {
+			return err
+		}
+		switch oauthError.Code {
+		case "authorization_pending":
+		case "slow_down":
+			interval += deviceDefaultInterval * deviceIntervalUnit
+		default:
+			return err
+		}
+	}
+}
+
+// ParsePrivateKeyPEM parses a PEM encoded RSA private key in either PKCS#1 or PKCS#8 form, e.g. the server.key file
+// generated for a connected app certificate.
+func ParsePrivateKeyPEM(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// signJWT builds and signs (RS256) the assertion used by the JWT bearer flow.
+func signJWT(issuer, subject, audience string, key *rsa.PrivateKey) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": issuer,
+		"sub": subject,
+		"aud": audience,
+		"exp": time.Now().Add(jwtExpiry).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	encoding := base64.RawURLEncoding
+	unsigned := encoding.EncodeToString(header) + "." + encoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + encoding.EncodeToString(signature), nil
+}
+
+// requestToken posts a token request to the OAuth token endpoint of loginURL and decodes the response.
+func (client *Client) requestToken(ctx context.Context, loginURL string, data url.Values) (*BearerTokenResponse, error) {
+	var token BearerTokenResponse
+	err := client.postOAuth(ctx, loginURL, data, &token)
+	if err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, ErrAuthentication
+	}
+	return &token, nil
+}
+
+// postOAuth posts form data to the OAuth token endpoint of loginURL and decodes the JSON response into v.
+func (client *Client) postOAuth(ctx context.Context, loginURL string, data url.Values, v interface{}) error {
+	endpoint := fmt.Sprintf("%s/services/oauth2/token", strings.TrimRight(loginURL, "/"))
+	payload := data.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Add("Content-Length", strconv.Itoa(len(payload)))
+
+	resp, err := client.do(req)
+	if err != nil {
+		client.log(LevelError, "error occurred submitting request", "url", endpoint, "error", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		client.log(LevelError, "oauth request failed", "url", endpoint, "status", resp.StatusCode)
+		return parseOAuthError(resp.StatusCode, body)
+	}
+
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return fmt.Errorf("error unmarshalling oauth response: %s", err)
+	}
+	return nil
+}
+
+// applyToken stores the session obtained from an OAuth token response on the client and in the token store. The login
+// URL and client ID the token was issued by are remembered so that the session can be refreshed later. The user and
+// the cached identity of the previous session are kept if the token belongs to the same user, e.g. when the session
+// is refreshed.
+func (client *Client) applyToken(ctx context.Context, token *BearerTokenResponse, loginURL, clientID, username string) error {
+	s := session{
+		id:            token.AccessToken,
+		refreshToken:  token.RefreshToken,
+		instanceURL:   parseHost(token.InstanceURL),
+		issuedAt:      parseIssuedAt(token.IssuedAt),
+		oauthURL:      loginURL,
+		oauthClientID: clientID,
+		identityURL:   token.ID,
+	}
+	s.user.id = identityUserID(token.ID)
+
+	client.mu.Lock()
+	previous := client.session
+	if s.refreshToken == previous.refreshToken {
+		s.sharedToken = previous.sharedToken
+	}
+	if s.identityURL == "" || s.identityURL == previous.identityURL {
+		s.identityURL = previous.identityURL
+		s.user = previous.user
+		s.identity = previous.identity
+	}
+	if username != "" {
+		s.user.name = username
+	}
+	client.session = s
+	client.mu.Unlock()
+
+	client.ensureAPIVersion(ctx)
+	return client.saveToken()
+}
+
+// refreshSession renews the session with the refresh token kept from the last OAuth login. staleID is the session
+// rejected by Salesforce; if another request renewed it meanwhile, the new session is used as is.
+func (client *Client) refreshSession(ctx context.Context, staleID string) error {
+	client.refreshMu.Lock()
+	defer client.refreshMu.Unlock()
+
+	s := client.currentSession()
+	if s.id != "" && s.id != staleID {
+		return nil
+	}
+	if s.refreshToken == "" {
+		return ErrAuthentication
+	}
+	err := client.redeemRefreshToken(ctx, s.oauthURL, s.oauthClientID, s.refreshToken)
+	if err != nil {
+		client.log(LevelError, "session refresh failed", "error", err)
+		return err
+	}
+	client.log(LevelInfo, "session refreshed")
+	return nil
+}
+
+// redeemRefreshToken exchanges refreshToken issued to clientID for a new session.
+func (client *Client) redeemRefreshToken(ctx context.Context, loginURL, clientID, refreshToken string) error {
+	data := url.Values{}
+	data.Set("grant_type", "refresh_token")
+	data.Set("refresh_token", refreshToken)
+	data.Set("client_id", clientID)
+	client.addClientSecret(data, clientID)
+
+	token, err := client.requestToken(ctx, loginURL, data)
+	if err != nil {
+		return err
+	}
+	if token.RefreshToken == "" {
+		// Salesforce does not rotate refresh tokens by default; keep using the one just redeemed.
+		token.RefreshToken = refreshToken
+	}
+	return client.applyToken(ctx, token, loginURL, clientID, "")
+}
+
+// addClientSecret adds the client secret to the token request data of clientID. The secret belongs to the connected
+// app of the client, so it is not sent along with another client ID, e.g. DefaultClientID for LoginWithAuthCode.
+func (client *Client) addClientSecret(data url.Values, clientID string) {
+	if client.clientSecret != "" && clientID == client.clientID {
+		data.Set("client_secret", client.clientSecret)
+	}
+}
+
+// revokeToken revokes an access or refresh token at the revoke endpoint of the instance.
+func (client *Client) revokeToken(ctx context.Context, token string) error {
+	data := url.Values{}
+	data.Set("token", token)
+	payload := data.Encode()
+
+	endpoint := fmt.Sprintf("%s/services/oauth2/revoke", client.currentSession().instanceURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.do(req)
+	if err != nil {
+		client.log(LevelError, "error occurred submitting request", "url", endpoint, "error", err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		client.log(LevelError, "oauth request failed", "url", endpoint, "status", resp.StatusCode)
+		body, _ := ioutil.ReadAll(resp.Body)
+		return parseOAuthError(resp.StatusCode, body)
+	}
+	return nil
+}
+
+// isInvalidSession returns if a failed response reports an expired or invalid session.
+func isInvalidSession(statusCode int, body []byte) bool {
+	return statusCode == http.StatusUnauthorized && parseAPIError(statusCode, body).HasCode(ErrInvalidSessionID)
+}
+
+// identityUserID extracts the user ID from an identity URL such as https://login.salesforce.com/id/<org>/<user>.
+func identityUserID(identityURL string) string {
+	parts := strings.Split(strings.TrimRight(identityURL, "/"), "/")
+	if len(parts) < 3 || parts[len(parts)-3] != "id" {
+		return ""
+	}
+	return parts[len(parts)-1]
+}
+
+// OAuthError is returned when an OAuth endpoint rejects a request, e.g.
+// {"error":"invalid_grant","error_description":"user hasn't approved this consumer"}.
+type OAuthError struct {
+	StatusCode  int
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return fmt.Sprintf("%s OAuth error. http code: %v Error: %v Description: %v",
+		logPrefix, e.StatusCode, e.Code, e.Description)
+}
+
+// Is reports every OAuth error as ErrAuthentication, as the OAuth endpoints only fail to grant or revoke tokens.
+func (e *OAuthError) Is(target error) bool {
+	return target == ErrAuthentication
+}
+
+// parseOAuthError converts an error response of the OAuth endpoints into an error.
+func parseOAuthError(statusCode int, body []byte) error {
+	var oauthError struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &oauthError); err != nil || oauthError.Error == "" {
+		return ParseSalesforceError(statusCode, body)
+	}
+	return &OAuthError{
+		StatusCode:  statusCode,
+		Code:        oauthError.Error,
+		Description: oauthError.Description,
+	}
+}
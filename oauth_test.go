@@ -0,0 +1,358 @@
+package simpleforce
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func requireRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// verifyJWT checks the RS256 signature of assertion and returns its claims. It runs in the handlers of test servers,
+// so it reports failures as errors rather than through t.
+func verifyJWT(assertion string, key *rsa.PublicKey) (map[string]interface{}, error) {
+	parts := strings.Split(assertion, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed assertion %q", assertion)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("signature verification failed, %v", err)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func TestClient_LoginJWT(t *testing.T) {
+	key := requireRSAKey(t)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/oauth2/token" || r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"unsupported_grant_type","error_description":"grant type not supported"}`)
+			return
+		}
+		claims, err := verifyJWT(r.FormValue("assertion"), &key.PublicKey)
+		if err != nil {
+			t.Errorf("invalid assertion: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant","error_description":"invalid assertion"}`)
+			return
+		}
+		if claims["iss"] != "__CONSUMER_KEY__" || claims["sub"] != "user@example.com" || claims["aud"] != server.URL {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant","error_description":"invalid assertion"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"__SESSION__","instance_url":"%s/path","id":"%s/id/00D000000000001/005000000000001","token_type":"Bearer"}`,
+			server.URL, server.URL)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/", "__CONSUMER_KEY__", DefaultAPIVersion)
+	err := client.LoginJWT("user@example.com", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.GetSid() != "__SESSION__" || client.GetLoc() != server.URL {
+		t.Fail()
+	}
+	if client.currentSession().user.id != "005000000000001" || client.currentSession().user.name != "user@example.com" {
+		t.Fail()
+	}
+
+	// Negative: the token endpoint rejects the assertion.
+	client = NewClient(server.URL, "__OTHER_KEY__", DefaultAPIVersion)
+	err = client.LoginJWT("user@example.com", key)
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Fail()
+	}
+	if client.isLoggedIn() {
+		t.Fail()
+	}
+
+	// Negative: no key.
+	if client.LoginJWT("user@example.com", nil) == nil {
+		t.Fail()
+	}
+}
+
+func TestParsePrivateKeyPEM(t *testing.T) {
+	key := requireRSAKey(t)
+
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	parsed, err := ParsePrivateKeyPEM(pkcs1)
+	if err != nil || !parsed.Equal(key) {
+		t.Fail()
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	parsed, err = ParsePrivateKeyPEM(pkcs8)
+	if err != nil || !parsed.Equal(key) {
+		t.Fail()
+	}
+
+	if _, err = ParsePrivateKeyPEM([]byte("not a key")); err == nil {
+		t.Fail()
+	}
+}
+
+func TestIdentityUserID(t *testing.T) {
+	if identityUserID("https://login.salesforce.com/id/00D8A000000MWVeUAO/0058A000008yLsfQAE") != "0058A000008yLsfQAE" {
+		t.Fail()
+	}
+	if identityUserID("https://login.salesforce.com/services/oauth2/userinfo") != "" {
+		t.Fail()
+	}
+	if identityUserID("") != "" {
+		t.Fail()
+	}
+}
+
+// newRefreshServer serves the token endpoint for the refresh token flow and an API resource that only accepts the
+// latest access token issued.
+func newRefreshServer() (*httptest.Server, *int) {
+	issued := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/services/oauth2/token":
+			grantType := r.FormValue("grant_type")
+			switch {
+			case r.FormValue("client_secret") != "":
+				// The secret of another connected app.
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"invalid_client","error_description":"invalid client credentials"}`)
+				return
+			case grantType == "authorization_code" && r.FormValue("code") == "__CODE__":
+			case grantType == "refresh_token" && r.FormValue("refresh_token") == "__REFRESH__" &&
+				r.FormValue("client_id") == DefaultClientID:
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"invalid_grant","error_description":"expired access/refresh token"}`)
+				return
+			}
+			issued++
+			refreshToken := ""
+			if grantType == "authorization_code" {
+				refreshToken = "__REFRESH__"
+			}
+			fmt.Fprintf(w, `{"access_token":"__SESSION_%d__","refresh_token":"%s","instance_url":"%s"}`,
+				issued, refreshToken, server.URL)
+		case "/services/data/v" + DefaultAPIVersion + "/sobjects/Case/__ID__":
+			if r.Header.Get("Authorization") != fmt.Sprintf("Bearer __SESSION_%d__", issued) {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `[{"message":"Session expired or invalid","errorCode":"INVALID_SESSION_ID"}]`)
+				return
+			}
+			fmt.Fprint(w, `{"attributes":{"type":"Case"},"Id":"__ID__","Subject":"simpleforce"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server, &issued
+}
+
+func TestClient_LoginWithRefreshToken(t *testing.T) {
+	server, issued := newRefreshServer()
+	defer server.Close()
+
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	err := client.LoginWithRefreshToken(server.URL, "__REFRESH__")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.GetSid() != "__SESSION_1__" || client.currentSession().refreshToken != "__REFRESH__" || *issued != 1 {
+		t.Fail()
+	}
+
+	// Negative: unknown refresh token.
+	client = NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	err = client.LoginWithRefreshToken(server.URL, "__INVALID__")
+	if err == nil || client.isLoggedIn() {
+		t.Fail()
+	}
+}
+
+func TestClient_SessionRefresh(t *testing.T) {
+	server, issued := newRefreshServer()
+	defer server.Close()
+
+	// The auth code and refresh requests use DefaultClientID, the secret of the client ID is not sent.
+	client := NewClient(server.URL, "__CONSUMER_KEY__", DefaultAPIVersion)
+	client.SetClientSecret("__SECRET__")
+	err := client.LoginWithAuthCode(server.URL, "__CODE__")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.currentSession().refreshToken != "__REFRESH__" {
+		t.Fatal("refresh token not kept")
+	}
+
+	// Expire the session: the server now only accepts the next token issued.
+	*issued++
+	obj := client.SObject("Case").Get("__ID__")
+	if obj == nil || obj.StringField("Subject") != "simpleforce" {
+		t.Fatal("request was not retried after refresh")
+	}
+	if client.GetSid() != "__SESSION_3__" {
+		t.Fail()
+	}
+
+	// Without a refresh token the error is returned as is.
+	client.SetSidLoc("__EXPIRED__", server.URL)
+	if client.SObject("Case").Get("__ID__") != nil {
+		t.Fail()
+	}
+}
+
+func TestClient_LoginClientCredentials(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "client_credentials" || r.FormValue("client_id") != "__CONSUMER_KEY__" ||
+			r.FormValue("client_secret") != "__SECRET__" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_client","error_description":"invalid client credentials"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"__SESSION__","instance_url":"%s","id":"%s/id/00D000000000001/005000000000002"}`,
+			server.URL, server.URL)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "__CONSUMER_KEY__", DefaultAPIVersion)
+	client.SetClientSecret("__SECRET__")
+	err := client.LoginClientCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.GetSid() != "__SESSION__" || client.currentSession().user.id != "005000000000002" {
+		t.Fail()
+	}
+
+	// Negative
+	client = NewClient(server.URL, "__CONSUMER_KEY__", DefaultAPIVersion)
+	client.SetClientSecret("__WRONG__")
+	err = client.LoginClientCredentials()
+	oauthError, ok := err.(*OAuthError)
+	if !ok || oauthError.Code != "invalid_client" || oauthError.StatusCode != http.StatusBadRequest {
+		t.Fail()
+	}
+
+	// Negative: no secret set.
+	client = NewClient(server.URL, "__CONSUMER_KEY__", DefaultAPIVersion)
+	if client.LoginClientCredentials() == nil || client.isLoggedIn() {
+		t.Fail()
+	}
+}
+
+func TestClient_LoginDevice(t *testing.T) {
+	defer func(unit time.Duration) { deviceIntervalUnit = unit }(deviceIntervalUnit)
+	deviceIntervalUnit = time.Millisecond
+
+	polls := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("response_type") == "device_code" {
+			if r.FormValue("client_id") != DefaultClientID || r.FormValue("scope") != "api" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"invalid_request","error_description":"invalid client id"}`)
+				return
+			}
+			fmt.Fprintf(w, `{"device_code":"__DEVICE__","user_code":"ABCD1234","verification_uri":"%s/setup/connect","interval":1}`,
+				server.URL)
+			return
+		}
+		if r.FormValue("grant_type") != "device" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		polls++
+		switch {
+		case r.FormValue("code") == "__DENIED__":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"access_denied","error_description":"end-user denied authorization"}`)
+		case polls == 1:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"authorization_pending","error_description":"authorization pending"}`)
+		default:
+			fmt.Fprintf(w, `{"access_token":"__SESSION__","refresh_token":"__REFRESH__","instance_url":"%s"}`, server.URL)
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var prompted *DeviceAuthorization
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	err := client.LoginDevice(ctx, "api", func(authorization *DeviceAuthorization) error {
+		prompted = authorization
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompted == nil || prompted.UserCode != "ABCD1234" || polls != 2 {
+		t.Fail()
+	}
+	if client.GetSid() != "__SESSION__" || client.currentSession().refreshToken != "__REFRESH__" {
+		t.Fail()
+	}
+
+	// Negative: user denies access.
+	client = NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	err = client.LoginDevice(ctx, "api", func(authorization *DeviceAuthorization) error {
+		authorization.DeviceCode = "__DENIED__"
+		return nil
+	})
+	if err == nil || client.isLoggedIn() {
+		t.Fail()
+	}
+
+	// Negative: prompt fails.
+	err = client.LoginDevice(ctx, "api", func(*DeviceAuthorization) error {
+		return ErrFailure
+	})
+	if err != ErrFailure {
+		t.Fail()
+	}
+}
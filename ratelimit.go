@@ -0,0 +1,125 @@
+package simpleforce
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket holding up to burst tokens, refilled at rate tokens per second.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// wait takes a token, waiting until one is available or ctx is done.
+func (limiter *rateLimiter) wait(ctx context.Context) error {
+	if limiter == nil {
+		return nil
+	}
+
+	limiter.mu.Lock()
+	now := time.Now()
+	limiter.tokens += now.Sub(limiter.last).Seconds() * limiter.rate
+	if limiter.tokens > limiter.burst {
+		limiter.tokens = limiter.burst
+	}
+	limiter.last = now
+	// Reserve the token right away, so that waiting requests are served in order.
+	limiter.tokens--
+	delay := time.Duration(-limiter.tokens / limiter.rate * float64(time.Second))
+	limiter.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	if err := sleepContext(ctx, delay); err != nil {
+		limiter.mu.Lock()
+		limiter.tokens++
+		limiter.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// SetRateLimit limits the client to rate requests per second, allowing bursts of up to burst requests. Requests
+// exceeding the rate wait for their turn or until their context is done. A rate of 0, the default, removes the limit.
+func (client *Client) SetRateLimit(rate float64, burst int) {
+	if rate <= 0 {
+		client.limiter = nil
+		return
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	client.limiter = &rateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// SetMaxConcurrentRequests caps the number of requests the client has in flight at n, e.g. to stay below the limit
+// Salesforce puts on concurrent long-running requests of an org. A request counts as in flight until its response is
+// read. Further requests wait for a slot or until their context is done. An n of 0, the default, removes the cap.
+func (client *Client) SetMaxConcurrentRequests(n int) {
+	if n <= 0 {
+		client.inFlight = nil
+		return
+	}
+	client.inFlight = make(chan struct{}, n)
+}
+
+// acquire takes a slot of inFlight, waiting until one is free or ctx is done. The returned function frees the slot.
+func acquire(ctx context.Context, inFlight chan struct{}) (func(), error) {
+	if inFlight == nil {
+		return func() {}, nil
+	}
+	select {
+	case inFlight <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-inFlight })
+	}, nil
+}
+
+// releaseOnClose frees the slot of a request once its response body is closed.
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (body *releaseOnClose) Close() error {
+	err := body.ReadCloser.Close()
+	body.release()
+	return err
+}
+
+// throttle waits for the rate limiter and a free slot before sending req with roundTrip.
+func (client *Client) throttle(req *http.Request, roundTrip RoundTripFunc) (*http.Response, error) {
+	ctx := req.Context()
+	if err := client.limiter.wait(ctx); err != nil {
+		return nil, err
+	}
+	release, err := acquire(ctx, client.inFlight)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := roundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
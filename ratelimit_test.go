@@ -0,0 +1,94 @@
+package simpleforce
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_wait(t *testing.T) {
+	client := NewClient(DefaultURL, DefaultClientID, DefaultAPIVersion)
+	client.SetRateLimit(100, 2)
+	ctx := context.Background()
+
+	// The burst is served right away, further requests at the rate.
+	started := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := client.limiter.wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(started); elapsed < 15*time.Millisecond || elapsed > time.Second {
+		t.Errorf("unexpected elapsed %v", elapsed)
+	}
+
+	// Waiting stops when the context is done, and the token is given back.
+	client.SetRateLimit(0.1, 1)
+	if err := client.limiter.wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := client.limiter.wait(timeout); err != context.DeadlineExceeded {
+		t.Fail()
+	}
+	if client.limiter.tokens < -0.01 || client.limiter.tokens > 0.01 {
+		t.Errorf("unexpected tokens %v", client.limiter.tokens)
+	}
+
+	client.SetRateLimit(0, 0)
+	if client.limiter != nil || client.limiter.wait(ctx) != nil {
+		t.Fail()
+	}
+}
+
+func TestClient_SetMaxConcurrentRequests(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		fmt.Fprint(w, `{"totalSize":0,"done":true,"records":[]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	client.SetSidLoc("__SESSION__", server.URL)
+	client.SetMaxConcurrentRequests(2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Query("SELECT Id FROM Case"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if maxInFlight != 2 || len(client.inFlight) != 0 {
+		t.Errorf("unexpected %d requests in flight, %d slots taken", maxInFlight, len(client.inFlight))
+	}
+
+	// Negative: waiting for a slot stops when the context is done.
+	client.inFlight <- struct{}{}
+	client.inFlight <- struct{}{}
+	timeout, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.QueryContext(timeout, "SELECT Id FROM Case"); err != context.DeadlineExceeded {
+		t.Errorf("unexpected %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package simpleforce
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// retryableErrorCodes lists the Salesforce error codes of transient failures which succeed when sent again later.
+var retryableErrorCodes = []ErrorCode{
+	ErrRequestLimitExceeded,
+	ErrUnableToLockRow,
+	ErrServerUnavailable,
+}
+
+// RetryPolicy controls how requests failing with transient errors are retried. Requests rejected with the
+// REQUEST_LIMIT_EXCEEDED, UNABLE_TO_LOCK_ROW and SERVER_UNAVAILABLE error codes, or whose connection could not be
+// established, were not applied and are always retried. Other 5xx responses, connection resets and timeouts may hide a
+// request which was applied, so they are only retried for the idempotent GET, HEAD, PUT and DELETE methods: a create
+// or an ExecuteAnonymous call is never sent twice. The delay between attempts grows exponentially from InitialBackoff
+// up to MaxBackoff, randomized by Jitter, unless the server asks for a specific delay with the Retry-After header.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one. Values below 2 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between two attempts.
+	MaxBackoff time.Duration
+
+	// MaxElapsed stops retrying once the time since the first attempt would exceed it. Zero means no limit.
+	MaxElapsed time.Duration
+
+	// Jitter is the fraction, between 0 and 1, by which each delay is randomly shortened to spread out retries of
+	// concurrent requests.
+	Jitter float64
+}
+
+// DefaultRetryPolicy returns a policy suitable for batch jobs: up to 5 attempts within 2 minutes.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		MaxElapsed:     2 * time.Minute,
+		Jitter:         0.2,
+	}
+}
+
+// SetRetryPolicy sets the policy for retrying API requests that fail with transient errors. Requests are not retried
+// if policy is nil, which is the default.
+func (client *Client) SetRetryPolicy(policy *RetryPolicy) {
+	client.retryPolicy = policy
+}
+
+// backoff returns the delay before the next attempt after attempt attempts failed, and false if no further attempt
+// should be made. retryAfter overrides the computed delay if positive.
+func (policy *RetryPolicy) backoff(attempt int, started time.Time, retryAfter time.Duration) (time.Duration, bool) {
+	if policy == nil || attempt >= policy.MaxAttempts {
+		return 0, false
+	}
+
+	delay := retryAfter
+	if delay <= 0 {
+		delay = policy.InitialBackoff
+		for i := 1; i < attempt && (policy.MaxBackoff <= 0 || delay < policy.MaxBackoff); i++ {
+			delay *= 2
+		}
+		if policy.MaxBackoff > 0 && delay > policy.MaxBackoff {
+			delay = policy.MaxBackoff
+		}
+		if policy.Jitter > 0 {
+			delay -= time.Duration(rand.Float64() * policy.Jitter * float64(delay))
+		}
+	}
+
+	if policy.MaxElapsed > 0 && time.Since(started)+delay > policy.MaxElapsed {
+		return 0, false
+	}
+	return delay, true
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// nonIdempotentKey is the context key marking the requests of a call which must not be sent twice although their
+// method is idempotent, such as the GET request of ExecuteAnonymous.
+type nonIdempotentKey struct{}
+
+// withNonIdempotent returns a copy of ctx marking its requests as not idempotent.
+func withNonIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, nonIdempotentKey{}, true)
+}
+
+// isIdempotent returns if a request of method bound to ctx can be sent again after it may have reached the server.
+func isIdempotent(ctx context.Context, method string) bool {
+	if nonIdempotent, _ := ctx.Value(nonIdempotentKey{}).(bool); nonIdempotent {
+		return false
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isRetryableError returns if err, returned by the HTTP client, is a transient network failure. Failures which may
+// have happened after the request reached the server are only retryable if the request is idempotent.
+func isRetryableError(err error, idempotent bool) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var opErr *net.OpError
+	if errors.Is(err, syscall.ECONNREFUSED) || (errors.As(err, &opErr) && opErr.Op == "dial") {
+		// The connection was never established.
+		return true
+	}
+	if !idempotent {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isRetryableResponse returns if a failed response reports a transient failure. The retryable error codes prove that
+// the request was not applied; other 5xx responses are only retryable if the request is idempotent.
+func isRetryableResponse(statusCode int, body []byte, idempotent bool) bool {
+	apiError := parseAPIError(statusCode, body)
+	for _, code := range retryableErrorCodes {
+		if apiError.HasCode(code) {
+			return true
+		}
+	}
+	return idempotent && statusCode >= 500
+}
+
+// retryAfter returns the delay requested by the Retry-After header of resp, if any.
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,216 @@
+package simpleforce
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func fastRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+		Jitter:         0.5,
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := &RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     3 * time.Second,
+	}
+	started := time.Now()
+	for attempt, expected := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		delay, ok := policy.backoff(attempt+1, started, 0)
+		if !ok || delay != expected {
+			t.Errorf("attempt %d: got %v, expected %v", attempt+1, delay, expected)
+		}
+	}
+	if _, ok := policy.backoff(5, started, 0); ok {
+		t.Fail()
+	}
+
+	// Retry-After takes precedence.
+	if delay, ok := policy.backoff(1, started, 7*time.Second); !ok || delay != 7*time.Second {
+		t.Fail()
+	}
+
+	// Jitter only shortens the delay.
+	policy.Jitter = 0.5
+	for i := 0; i < 10; i++ {
+		delay, ok := policy.backoff(1, started, 0)
+		if !ok || delay > time.Second || delay < 500*time.Millisecond {
+			t.Fail()
+		}
+	}
+
+	// Elapsed time cap.
+	policy.MaxElapsed = time.Minute
+	if _, ok := policy.backoff(1, started.Add(-time.Minute), 0); ok {
+		t.Fail()
+	}
+
+	// No policy, no retries.
+	var none *RetryPolicy
+	if _, ok := none.backoff(1, started, 0); ok {
+		t.Fail()
+	}
+}
+
+func TestClient_httpRequestRetry(t *testing.T) {
+	// The handler may still run when a reset connection fails the request, so the counters are guarded.
+	var mu sync.Mutex
+	failures := map[string]int{}
+	attempts := 0
+	reset := func(code string, n int) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = 0
+		failures[code] = n
+	}
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return attempts
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		body, _ := ioutil.ReadAll(r.Body)
+		if string(body) != `{"Subject":"simpleforce"}` {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `[{"message":"body not replayed","errorCode":"JSON_PARSER_ERROR"}]`)
+			return
+		}
+		code := r.URL.Query().Get("fail")
+		if failures[code] > 0 {
+			failures[code]--
+			switch code {
+			case "503":
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprint(w, `[{"message":"Server unavailable","errorCode":"SERVER_UNAVAILABLE"}]`)
+			case "500":
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprint(w, `[{"message":"An unexpected error occurred","errorCode":"UNKNOWN_EXCEPTION"}]`)
+			case "lock":
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `[{"message":"unable to obtain exclusive access to this record","errorCode":"UNABLE_TO_LOCK_ROW"}]`)
+			case "reset":
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `[{"message":"Required fields are missing","errorCode":"REQUIRED_FIELD_MISSING"}]`)
+			}
+			return
+		}
+		fmt.Fprint(w, `{"id":"__ID__","success":true}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	client.SetSidLoc("__SESSION__", server.URL)
+	body := []byte(`{"Subject":"simpleforce"}`)
+	ctx := context.Background()
+
+	// Without a retry policy, failures are returned right away.
+	reset("503", 1)
+	if _, err := client.httpRequest(ctx, http.MethodPost, server.URL+"?fail=503", body); err == nil || count() != 1 {
+		t.Fail()
+	}
+
+	client.SetRetryPolicy(fastRetryPolicy())
+	for _, code := range []string{"503", "lock", "500", "reset"} {
+		reset(code, 2)
+		_, err := client.httpRequest(ctx, http.MethodPut, server.URL+"?fail="+code, body)
+		if err != nil || count() != 3 {
+			t.Errorf("%s: %v after %d attempts", code, err, count())
+		}
+	}
+
+	// Requests which are not idempotent are only retried if the error proves they were not applied.
+	for _, code := range []string{"503", "lock"} {
+		reset(code, 2)
+		_, err := client.httpRequest(ctx, http.MethodPost, server.URL+"?fail="+code, body)
+		if err != nil || count() != 3 {
+			t.Errorf("%s: %v after %d attempts", code, err, count())
+		}
+	}
+	for _, code := range []string{"500", "reset"} {
+		reset(code, 1)
+		_, err := client.httpRequest(ctx, http.MethodPost, server.URL+"?fail="+code, body)
+		if err == nil || count() != 1 {
+			t.Errorf("%s: %v after %d attempts", code, err, count())
+		}
+		reset(code, 0)
+	}
+
+	// Attempts are capped.
+	reset("503", 3)
+	if _, err := client.httpRequest(ctx, http.MethodPost, server.URL+"?fail=503", body); err == nil || count() != 3 {
+		t.Fail()
+	}
+
+	// Permanent failures are not retried.
+	reset("missing", 1)
+	if _, err := client.httpRequest(ctx, http.MethodPost, server.URL+"?fail=missing", body); err == nil || count() != 1 {
+		t.Fail()
+	}
+
+	// Waiting for the next attempt stops when the context is done.
+	client.SetRetryPolicy(&RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Hour})
+	reset("503", 1)
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.httpRequest(timeout, http.MethodPost, server.URL+"?fail=503", body); err != context.DeadlineExceeded {
+		t.Fail()
+	}
+}
+
+func TestClient_CreateNotRetried(t *testing.T) {
+	var creates int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&creates, 1)
+		// The record is created, but the connection is reset before the response is sent.
+		ioutil.ReadAll(r.Body)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	client.SetSidLoc("__SESSION__", server.URL)
+	client.SetRetryPolicy(fastRetryPolicy())
+
+	if _, err := client.SObject("Case").Set("Subject", "simpleforce").CreateContext(context.Background()); err == nil {
+		t.Fail()
+	}
+	if n := atomic.LoadInt32(&creates); n != 1 {
+		t.Errorf("create sent %d times", n)
+	}
+	if _, err := client.ExecuteAnonymous("insert new Case();"); err == nil || atomic.LoadInt32(&creates) != 2 {
+		t.Errorf("anonymous apex sent %d times: %v", atomic.LoadInt32(&creates)-1, err)
+	}
+
+	// Connections which cannot be established are retried, the request never reached the server.
+	server.Close()
+	attempts := 0
+	client.Use(func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			attempts++
+			return next(req)
+		}
+	})
+	if _, err := client.SObject("Case").Set("Subject", "simpleforce").CreateContext(context.Background()); err == nil || attempts != 3 {
+		t.Errorf("%v after %d attempts", err, attempts)
+	}
+}
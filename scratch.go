@@ -6,7 +6,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"math/rand"
 	"strconv"
 	"strings"
@@ -81,13 +80,25 @@ func generatePassword(passwordLength, minSpecialChar, minNum, minUpperCase int)
 
 // HasScratch creates scratch with given OrgName
 func (client *Client) HasScratch(name string) (bool, string, error) {
+	return client.HasScratchContext(context.Background(), name)
+}
+
+// HasScratchContext is like HasScratch, the query is bound to ctx.
+func (client *Client) HasScratchContext(ctx context.Context, name string) (bool, string, error) {
 	if !client.isLoggedIn() {
 		return false, "", ErrAuthentication
 	}
 
 	// Query active org by OrgName first!
-	q := fmt.Sprintf("SELECT FIELDS(ALL) FROM ScratchOrgInfo WHERE OrgName = '%s' AND Status = 'Active' LIMIT 2", name)
-	result, err := client.Query(q)
+	q, err := Select("FIELDS(ALL)").From("ScratchOrgInfo").
+		Where("OrgName = ?", name).
+		Where("Status = 'Active'").
+		Limit(2).
+		Build()
+	if err != nil {
+		return false, "", err
+	}
+	result, err := client.QueryContext(ctx, q)
 	if err != nil {
 		return false, "", err
 	}
@@ -101,12 +112,20 @@ func (client *Client) HasScratch(name string) (bool, string, error) {
 }
 
 func (client *Client) Scratches() (scratches []SObject, err error) {
+	return client.ScratchesContext(context.Background())
+}
+
+// ScratchesContext is like Scratches, the query is bound to ctx.
+func (client *Client) ScratchesContext(ctx context.Context) (scratches []SObject, err error) {
 	if !client.isLoggedIn() {
 		return scratches, ErrAuthentication
 	}
 
-	q := "SELECT FIELDS(ALL) FROM ScratchOrgInfo WHERE Status = 'Active' LIMIT 40"
-	result, err := client.Query(q)
+	q, err := Select("FIELDS(ALL)").From("ScratchOrgInfo").Where("Status = 'Active'").Limit(40).Build()
+	if err != nil {
+		return scratches, err
+	}
+	result, err := client.QueryContext(ctx, q)
 	if err != nil {
 		return scratches, fmt.Errorf("error to query scratches in salesforce devhub: %s", err)
 	}
@@ -155,6 +174,12 @@ func buildIPRangesXML(ipRanges []IPRange) string {
 }
 
 func (client *Client) CreateScratch(params CreateScratchParams) (*CreateScratchResult, error) {
+	return client.CreateScratchContext(context.Background(), params)
+}
+
+// CreateScratchContext is like CreateScratch. Creation gives up as soon as ctx is done, including while waiting for
+// the new org to become active.
+func (client *Client) CreateScratchContext(ctx context.Context, params CreateScratchParams) (*CreateScratchResult, error) {
 	if !client.isLoggedIn() {
 		return nil, ErrAuthentication
 	}
@@ -188,9 +213,11 @@ func (client *Client) CreateScratch(params CreateScratchParams) (*CreateScratchR
         );
         insert(newScratch);
         `
-		apexBody := fmt.Sprintf(apexBodyTemplate, params.Name, edition, params.Username, params.AdminEmail, DefaultClientID,
-			DefaultRedirectURI, durationDays, params.Features, params.Description, params.CountryCode, params.Release)
-		_, err := client.ExecuteAnonymous(apexBody)
+		apexBody := fmt.Sprintf(apexBodyTemplate, EscapeString(params.Name), EscapeString(edition),
+			EscapeString(params.Username), EscapeString(params.AdminEmail), DefaultClientID, DefaultRedirectURI, durationDays,
+			EscapeString(params.Features), EscapeString(params.Description), EscapeString(params.CountryCode),
+			EscapeString(params.Release))
+		_, err := client.ExecuteAnonymousContext(ctx, apexBody)
 		if err != nil {
 			return nil, err
 		}
@@ -212,8 +239,10 @@ func (client *Client) CreateScratch(params CreateScratchParams) (*CreateScratchR
         );
         insert(newScratch);
         `
-		apexBody := fmt.Sprintf(apexBodyTemplate, params.Name, params.Username, params.AdminEmail, DefaultClientID, DefaultRedirectURI, params.Features, params.Description, params.Namespace, params.CountryCode)
-		_, err := client.ExecuteAnonymous(apexBody)
+		apexBody := fmt.Sprintf(apexBodyTemplate, EscapeString(params.Name), EscapeString(params.Username),
+			EscapeString(params.AdminEmail), DefaultClientID, DefaultRedirectURI, EscapeString(params.Features),
+			EscapeString(params.Description), EscapeString(params.Namespace), EscapeString(params.CountryCode))
+		_, err := client.ExecuteAnonymousContext(ctx, apexBody)
 		if err != nil {
 			return nil, fmt.Errorf("Error creating scratch org: %s", err)
 		}
@@ -221,18 +250,27 @@ func (client *Client) CreateScratch(params CreateScratchParams) (*CreateScratchR
 
 	var err error
 	result := &QueryResult{}
-	ctxTimeout, cancel := context.WithTimeout(context.Background(), time.Minute*6)
+	ctxTimeout, cancel := context.WithTimeout(ctx, time.Minute*6)
 	defer cancel()
 	for {
-		time.Sleep(10 * time.Second)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Second):
+		}
 
 		// Query newly created Org
-		q := fmt.Sprintf(
-			"SELECT FIELDS(ALL) FROM ScratchOrgInfo "+
-				"WHERE OrgName = '%s' AND Username = '%s' AND Status != 'Deleted' LIMIT 2",
-			params.Name, params.Username,
-		)
-		result, err = client.Query(q)
+		var q string
+		q, err = Select("FIELDS(ALL)").From("ScratchOrgInfo").
+			Where("OrgName = ?", params.Name).
+			Where("Username = ?", params.Username).
+			Where("Status != 'Deleted'").
+			Limit(2).
+			Build()
+		if err != nil {
+			return nil, err
+		}
+		result, err = client.QueryContext(ctx, q)
 		if err != nil {
 			return nil, err
 		}
@@ -244,7 +282,7 @@ func (client *Client) CreateScratch(params CreateScratchParams) (*CreateScratchR
 			qResult := result.Records[0]
 			status := qResult.StringField("Status")
 			if status == "Active" {
-				log.Printf("Org %s is active, all is ok", params.Name)
+				client.log(LevelInfo, "scratch org is active", "org", params.Name)
 				break
 			}
 
@@ -255,16 +293,18 @@ func (client *Client) CreateScratch(params CreateScratchParams) (*CreateScratchR
 					params.Name, errorCode,
 				)
 			}
-			log.Printf("Got status %s for org %s when creating with user %s, waiting...", status, params.Name, params.Username)
+			client.log(LevelDebug, "waiting for scratch org", "org", params.Name, "user", params.Username, "status", status)
 		}
 
 		if len(result.Records) == 0 {
-			log.Printf("Org %s not Found after just created", params.Name)
+			client.log(LevelWarn, "scratch org not found after just created", "org", params.Name)
 		}
 
 		select {
 		case <-ctxTimeout.Done():
-
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			return nil, fmt.Errorf("Giving up checking %s after creation, not found, waited 6 minutes", params.Name)
 
 		default:
@@ -283,14 +323,14 @@ func (client *Client) CreateScratch(params CreateScratchParams) (*CreateScratchR
 		Features:  existingOrg.StringField("Features"),
 	}
 
-	scratchClient := NewClient(output.LoginURL, DefaultClientID, DefaultAPIVersion)
-	err = scratchClient.LoginWithAuthCode(output.LoginURL, output.AuthCode)
+	scratchClient := NewClient(output.LoginURL, DefaultClientID, client.version())
+	err = scratchClient.LoginWithAuthCodeContext(ctx, output.LoginURL, output.AuthCode)
 	if err != nil {
 		return &CreateScratchResult{Success: false},
 			errors.New(fmt.Sprintf("AuthCode auth failed just after org creation: %s", err))
 	}
 
-	err = scratchClient.ApplySecuritySettings(ApplySecuritySettingsParams{
+	err = scratchClient.ApplySecuritySettingsContext(ctx, ApplySecuritySettingsParams{
 		EnableAuditFieldsInactiveOwner: params.Settings.EnableAuditFieldsInactiveOwner,
 		IPRanges:                       params.Settings.IPRanges,
 	})
@@ -303,8 +343,8 @@ func (client *Client) CreateScratch(params CreateScratchParams) (*CreateScratchR
 	apexBodyTemplate = `
       System.setPassword(userInfo.getUserId(),'%s');
     `
-	apexBody = fmt.Sprintf(apexBodyTemplate, pass)
-	_, err = scratchClient.ExecuteAnonymous(apexBody)
+	apexBody = fmt.Sprintf(apexBodyTemplate, EscapeString(pass))
+	_, err = scratchClient.ExecuteAnonymousContext(ctx, apexBody)
 	if err != nil {
 		return &CreateScratchResult{Success: false}, err
 	}
@@ -322,8 +362,8 @@ func (client *Client) CreateScratch(params CreateScratchParams) (*CreateScratchR
       user.CurrencyIsoCode = 'USD';
       update user;
     `
-	apexBody = fmt.Sprintf(apexBodyTemplate, params.CountryName)
-	_, err = scratchClient.ExecuteAnonymous(apexBody)
+	apexBody = fmt.Sprintf(apexBodyTemplate, EscapeString(params.CountryName))
+	_, err = scratchClient.ExecuteAnonymousContext(ctx, apexBody)
 	if err != nil {
 		return &output, fmt.Errorf("Error setting user details: %s", err)
 	}
@@ -337,6 +377,11 @@ type ApplySecuritySettingsParams struct {
 }
 
 func (client *Client) ApplySecuritySettings(params ApplySecuritySettingsParams) error {
+	return client.ApplySecuritySettingsContext(context.Background(), params)
+}
+
+// ApplySecuritySettingsContext is like ApplySecuritySettings, the deployment is bound to ctx.
+func (client *Client) ApplySecuritySettingsContext(ctx context.Context, params ApplySecuritySettingsParams) error {
 	// APPLY Security settings to allow authorizing without 2FA
 	/* zip layout:
 	package.xml
@@ -358,7 +403,7 @@ func (client *Client) ApplySecuritySettings(params ApplySecuritySettingsParams)
 	var files = []struct {
 		Name, Body string
 	}{
-		{"package.xml", ScratchPackageXML},
+		{"package.xml", scratchPackageXML(client.version())},
 		{"settings/Quote.settings", ScratchQuoteSettingsMeta},
 		{"settings/Security.settings", ScratchSecuritySettingsMeta},
 		{"settings/Currency.settings", ScratchCurrencySettingsMeta},
@@ -381,7 +426,7 @@ func (client *Client) ApplySecuritySettings(params ApplySecuritySettingsParams)
 		return err
 	}
 
-	res, err := client.MetaDeploy(buf.Bytes(), "NoTestRun")
+	res, err := client.MetaDeployContext(ctx, buf.Bytes(), "NoTestRun")
 	if err != nil {
 		return err
 	}
@@ -417,17 +462,30 @@ func (client *Client) ApplySecuritySettings(params ApplySecuritySettingsParams)
 }
 
 func (client *Client) RemoveScratch(name string) (*RemoveScratchResult, error) {
+	return client.RemoveScratchContext(context.Background(), name)
+}
+
+// RemoveScratchContext is like RemoveScratch, the request is bound to ctx.
+func (client *Client) RemoveScratchContext(ctx context.Context, name string) (*RemoveScratchResult, error) {
 	if !client.isLoggedIn() {
 		return nil, ErrAuthentication
 	}
 
+	q, err := Select("Id").From("ScratchOrgInfo").
+		Where("OrgName = ?", name).
+		Where("Status = 'Active'").
+		Limit(10).
+		Build()
+	if err != nil {
+		return nil, err
+	}
 	apexBodyTemplate := `
-      ScratchOrgInfo[] orgs = [SELECT Id FROM ScratchOrgInfo WHERE OrgName = '%s' AND Status = 'Active' LIMIT 10];
+      ScratchOrgInfo[] orgs = [%s];
       delete orgs;
     `
-	apexBody := fmt.Sprintf(apexBodyTemplate, name)
+	apexBody := fmt.Sprintf(apexBodyTemplate, q)
 
-	_, err := client.ExecuteAnonymous(apexBody)
+	_, err = client.ExecuteAnonymousContext(ctx, apexBody)
 	if err != nil {
 		return nil, err
 	}
@@ -435,6 +493,11 @@ func (client *Client) RemoveScratch(name string) (*RemoveScratchResult, error) {
 	return &RemoveScratchResult{Success: true}, nil
 }
 
+// scratchPackageXML returns ScratchPackageXML for the API version of the deployment.
+func scratchPackageXML(version string) string {
+	return strings.Replace(ScratchPackageXML, "<version>53.0</version>", "<version>"+version+"</version>", 1)
+}
+
 const ScratchPackageXML = `<?xml version="1.0" encoding="UTF-8"?>
 <Package xmlns="http://soap.sforce.com/2006/04/metadata">
     <types>
@@ -1,9 +1,15 @@
 package simpleforce
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/veloceapps/simpleforce/simpleforcetest"
 )
 
 func TestClient_CreateScratch(t *testing.T) {
@@ -104,3 +110,57 @@ func TestScratchesList(t *testing.T) {
 	}
 	fmt.Printf("Result: %+v", result)
 }
+
+func TestClient_CreateScratchContext(t *testing.T) {
+	executed := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Path, "/tooling/executeAnonymous") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		executed++
+		fmt.Fprint(w, `{"line":-1,"column":-1,"compiled":true,"success":true,"compileProblem":null,"exceptionStackTrace":null,"exceptionMessage":null}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	client.SetSidLoc("__SESSION__", server.URL)
+
+	// Waiting for the org to become active is abandoned once the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := client.CreateScratchContext(ctx, CreateScratchParams{Name: "simpleforce", Username: "user@example.com"})
+	if err != context.DeadlineExceeded || executed != 1 {
+		t.Fail()
+	}
+}
+
+func TestClient_HasScratchEscaping(t *testing.T) {
+	server := simpleforcetest.NewServer()
+	defer server.Close()
+	server.Insert("ScratchOrgInfo", map[string]interface{}{
+		"OrgName": "O'Brien Scratch", "Status": "Active", "ExpirationDate": "2024-02-01",
+	})
+	server.Insert("ScratchOrgInfo", map[string]interface{}{
+		"OrgName": "Other", "Status": "Active", "ExpirationDate": "2024-03-01",
+	})
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	client.SetSidLoc(server.NewSession(), server.URL)
+
+	found, expires, err := client.HasScratch("O'Brien Scratch")
+	if err != nil || !found || expires != "2024-02-01" {
+		t.Errorf("unexpected %v %q %v", found, expires, err)
+	}
+
+	// Negative: quotes in the name cannot change the query.
+	if found, _, err = client.HasScratch("x' OR OrgName != '"); err != nil || found {
+		t.Errorf("unexpected %v %v", found, err)
+	}
+	if _, err = client.RemoveScratch("x' OR OrgName != '"); err != nil {
+		t.Fatal(err)
+	}
+	want := `[SELECT Id FROM ScratchOrgInfo WHERE (OrgName = 'x\' OR OrgName != \'') AND (Status = 'Active') LIMIT 10]`
+	if apex := server.ExecutedApex(); len(apex) != 1 || !strings.Contains(apex[0], want) {
+		t.Errorf("unexpected apex %q", apex)
+	}
+}
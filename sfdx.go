@@ -0,0 +1,109 @@
+package simpleforce
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const authURLScheme = "force://"
+
+// AuthURL holds the parts of an SFDX auth URL, force://<clientId>:<clientSecret>:<refreshToken>@<instanceUrl>, as
+// printed by `sf org display --verbose`.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.sfdx_cli_reference.meta/sfdx_cli_reference/cli_reference_org_commands_unified.htm
+type AuthURL struct {
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+	InstanceURL  string
+}
+
+// ParseAuthURL splits an SFDX auth URL into its parts. The client secret may be empty, e.g. for the PlatformCLI
+// connected app used by the Salesforce CLI.
+func ParseAuthURL(authURL string) (*AuthURL, error) {
+	authURL = strings.TrimSpace(authURL)
+	if !strings.HasPrefix(authURL, authURLScheme) {
+		return nil, errors.New("invalid auth url: missing " + authURLScheme + " prefix")
+	}
+
+	rest := strings.TrimPrefix(authURL, authURLScheme)
+	at := strings.LastIndex(rest, "@")
+	if at == -1 {
+		return nil, errors.New("invalid auth url: missing instance url")
+	}
+	credentials, instanceURL := rest[:at], rest[at+1:]
+
+	parts := strings.SplitN(credentials, ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return nil, errors.New("invalid auth url: expected <clientId>:<clientSecret>:<refreshToken>")
+	}
+	if instanceURL == "" {
+		return nil, errors.New("invalid auth url: missing instance url")
+	}
+	if !strings.Contains(instanceURL, "://") {
+		instanceURL = "https://" + instanceURL
+	}
+
+	return &AuthURL{
+		ClientID:     parts[0],
+		ClientSecret: parts[1],
+		RefreshToken: parts[2],
+		InstanceURL:  strings.TrimRight(instanceURL, "/"),
+	}, nil
+}
+
+// NewClientFromAuthURL creates a client from an SFDX auth URL and logs in with the refresh token it carries. This
+// allows orgs already authorized with the Salesforce CLI to be reused without another login flow. The latest version
+// supported by the org is used if apiVersion is empty. The refresh token stays valid on Logout, which would otherwise
+// sign the CLI out too.
+func NewClientFromAuthURL(authURL, apiVersion string) (*Client, error) {
+	parsed, err := ParseAuthURL(authURL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewClient(parsed.InstanceURL, parsed.ClientID, apiVersion)
+	client.SetClientSecret(parsed.ClientSecret)
+	// Mark the refresh token as shared before logging in, applyToken keeps the mark as long as it is used.
+	client.setSession(session{refreshToken: parsed.RefreshToken, sharedToken: true})
+	err = client.LoginWithRefreshToken(parsed.InstanceURL, parsed.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewClientFromAuthURLFile is like NewClientFromAuthURL, but reads the auth URL from a file. Same as for
+// `sf org login sfdx-url --sfdx-url-file`, the file may either contain the auth URL itself or the JSON output of
+// `sf org display --verbose --json`.
+func NewClientFromAuthURLFile(path, apiVersion string) (*Client, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	authURL := string(data)
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var display struct {
+			SfdxAuthURL string `json:"sfdxAuthUrl"`
+			Result      struct {
+				SfdxAuthURL string `json:"sfdxAuthUrl"`
+			} `json:"result"`
+		}
+		err = json.Unmarshal(trimmed, &display)
+		if err != nil {
+			return nil, err
+		}
+		authURL = display.SfdxAuthURL
+		if authURL == "" {
+			authURL = display.Result.SfdxAuthURL
+		}
+		if authURL == "" {
+			return nil, errors.New("no sfdxAuthUrl found in " + path)
+		}
+	}
+	return NewClientFromAuthURL(authURL, apiVersion)
+}
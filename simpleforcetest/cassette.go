@@ -0,0 +1,284 @@
+package simpleforcetest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Mode selects whether a Recorder captures or serves traffic.
+type Mode int
+
+const (
+	// ModeReplay serves the interactions of an existing cassette without any network access.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests to Salesforce and captures the interactions, saved to the cassette by Stop.
+	ModeRecord
+)
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a scrubbed request.
+type RecordedRequest struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 string      `json:"body_base64,omitempty"` // binary bodies, e.g. metadata zip files.
+}
+
+// RecordedResponse is a scrubbed response.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 string      `json:"body_base64,omitempty"`
+}
+
+// Scrubbed replaces the secrets removed from cassettes.
+const Scrubbed = "SCRUBBED"
+
+// secretPatterns match session IDs, access, refresh and ID tokens, and passwords in URLs and bodies. The first group is kept.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(<(?:\w+:)?(?:sessionId|password)>)[^<]*`),
+	regexp.MustCompile(`("(?:access_token|refresh_token|id_token|client_secret|password|sessionId|signature)"\s*:\s*")[^"]*`),
+	regexp.MustCompile(`\b((?:access_token|refresh_token|id_token|client_secret|password|code|code_verifier|assertion|sid|token)=)[^&\s"]*`),
+	regexp.MustCompile(`()\b00D\w{12,15}![\w.]+`),
+}
+
+// secretHeaders are the headers whose values are never recorded.
+var secretHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// Recorder is an http.RoundTripper recording Salesforce traffic to a cassette file, or replaying it, e.g. installed
+// with simpleforce.Client.SetHttpClient(recorder.Client()). Session IDs, tokens and passwords are scrubbed from the
+// recorded interactions. Replayed requests are matched by method and URL, in the order they were recorded.
+// A Recorder is safe for concurrent use.
+type Recorder struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+	scrubs   []string // pairs of literal secrets and their placeholders.
+}
+
+// NewRecorder returns a Recorder for the cassette file at path. In ModeRecord, requests are sent with transport,
+// http.DefaultTransport if nil. In ModeReplay, the cassette is loaded and transport is not used.
+func NewRecorder(path string, mode Mode, transport http.RoundTripper) (*Recorder, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	recorder := &Recorder{path: path, mode: mode, transport: transport}
+	if mode == ModeReplay {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(data, &recorder.cassette); err != nil {
+			return nil, fmt.Errorf("invalid cassette %s: %v", path, err)
+		}
+		recorder.used = make([]bool, len(recorder.cassette.Interactions))
+	}
+	return recorder, nil
+}
+
+// ScrubString replaces a literal secret, e.g. a username or an org domain, with placeholder in the recorded
+// interactions and in the URLs matched on replay. It must be called before the first request.
+func (recorder *Recorder) ScrubString(secret, placeholder string) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.scrubs = append(recorder.scrubs, secret, placeholder)
+}
+
+// Client returns an HTTP client using the recorder as transport.
+func (recorder *Recorder) Client() *http.Client {
+	return &http.Client{Transport: recorder}
+}
+
+// Interactions returns the interactions recorded or loaded so far.
+func (recorder *Recorder) Interactions() []Interaction {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return append([]Interaction(nil), recorder.cassette.Interactions...)
+}
+
+// Stop saves the recorded interactions to the cassette file in ModeRecord. It does nothing in ModeReplay.
+func (recorder *Recorder) Stop() error {
+	if recorder.mode != ModeRecord {
+		return nil
+	}
+	recorder.mu.Lock()
+	data, err := json.MarshalIndent(recorder.cassette, "", "  ")
+	recorder.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(recorder.path), 0o755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(recorder.path, data, 0o644)
+}
+
+// RoundTrip records or replays a request.
+func (recorder *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if recorder.mode == ModeReplay {
+		return recorder.replay(req)
+	}
+	return recorder.record(req, body)
+}
+
+func (recorder *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.Body = ioutil.NopCloser(bytes.NewReader(body))
+	resp, err := recorder.transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	header := resp.Header.Clone()
+	if strings.EqualFold(header.Get("Content-Encoding"), "gzip") {
+		// Cassettes hold plain text so that they can be scrubbed and reviewed.
+		reader, err := gzip.NewReader(bytes.NewReader(respBody))
+		if err == nil {
+			respBody, err = ioutil.ReadAll(reader)
+		}
+		if err != nil {
+			return nil, err
+		}
+		header.Del("Content-Encoding")
+	}
+	// Scrubbing changes the length of the body.
+	header.Del("Content-Length")
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    recorder.scrub(req.URL.String()),
+			Header: recorder.scrubHeader(req.Header),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     recorder.scrubHeader(header),
+		},
+	}
+	interaction.Request.Body, interaction.Request.BodyBase64 = recorder.encodeBody(body)
+	interaction.Response.Body, interaction.Response.BodyBase64 = recorder.encodeBody(respBody)
+
+	recorder.mu.Lock()
+	recorder.cassette.Interactions = append(recorder.cassette.Interactions, interaction)
+	recorder.mu.Unlock()
+
+	// The client sees the unscrubbed response.
+	return newResponse(req, resp.StatusCode, header, respBody), nil
+}
+
+func (recorder *Recorder) replay(req *http.Request) (*http.Response, error) {
+	url := recorder.scrub(req.URL.String())
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	for i, interaction := range recorder.cassette.Interactions {
+		if recorder.used[i] || interaction.Request.Method != req.Method || interaction.Request.URL != url {
+			continue
+		}
+		recorder.used[i] = true
+		body := []byte(interaction.Response.Body)
+		if interaction.Response.BodyBase64 != "" {
+			var err error
+			body, err = base64.StdEncoding.DecodeString(interaction.Response.BodyBase64)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return newResponse(req, interaction.Response.StatusCode, interaction.Response.Header.Clone(), body), nil
+	}
+	return nil, fmt.Errorf("simpleforcetest: no recorded interaction for %s %s in %s", req.Method, url, recorder.path)
+}
+
+// scrub removes the secrets from s.
+func (recorder *Recorder) scrub(s string) string {
+	recorder.mu.Lock()
+	scrubs := recorder.scrubs
+	recorder.mu.Unlock()
+	for i := 0; i < len(scrubs); i += 2 {
+		s = strings.Replace(s, scrubs[i], scrubs[i+1], -1)
+	}
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllString(s, "${1}"+Scrubbed)
+	}
+	return s
+}
+
+func (recorder *Recorder) scrubHeader(header http.Header) http.Header {
+	out := http.Header{}
+	for key, values := range header {
+		for _, value := range values {
+			out.Add(key, recorder.scrub(value))
+		}
+	}
+	for _, key := range secretHeaders {
+		if out.Get(key) != "" {
+			out.Set(key, Scrubbed)
+		}
+	}
+	return out
+}
+
+// encodeBody returns a scrubbed text body, or a binary body encoded in base64.
+func (recorder *Recorder) encodeBody(body []byte) (text string, binary string) {
+	if !utf8.Valid(body) {
+		return "", base64.StdEncoding.EncodeToString(body)
+	}
+	return recorder.scrub(string(body)), ""
+}
+
+func newResponse(req *http.Request, statusCode int, header http.Header, body []byte) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,117 @@
+package simpleforcetest
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/veloceapps/simpleforce"
+)
+
+// exercise runs the calls recorded and replayed by TestRecorder.
+func exercise(t *testing.T, client *simpleforce.Client) {
+	ctx := context.Background()
+	if err := client.LoginPassword(Username, "s3cret", "TOKEN123"); err != nil {
+		t.Fatal(err)
+	}
+	result, err := client.Query("SELECT Id, Name FROM Account")
+	if err != nil || result.TotalSize != 1 || result.Records[0].StringField("Name") != "Acme" {
+		t.Fatalf("unexpected %+v %v", result, err)
+	}
+	created, err := client.SObject("Case").Set("Subject", "Broken").CreateContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.SObject("Case").Set("Id", created.ID()).Set("Subject", "Fixed").UpdateContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got, err := client.SObject("Case").GetContext(ctx, created.ID())
+	if err != nil || got.StringField("Subject") != "Fixed" {
+		t.Fatalf("unexpected %v %v", got, err)
+	}
+	if err = client.SObject("Case").Set("Id", created.ID()).Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.ExecuteAnonymous("System.debug('ok');"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.MetaDeploy([]byte{'P', 'K', 0x03, 0x04, 0xff}, "NoTestRun"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "crud.json")
+	server := NewServer()
+	server.SetCredentials(Username, "s3cretTOKEN123")
+	server.Insert("Account", map[string]interface{}{"Name": "Acme"})
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	// Record the traffic with the fake server.
+	recorder, err := NewRecorder(path, ModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.ScrubString(host, "example.my.salesforce.com")
+	client := simpleforce.NewClient(server.URL, simpleforce.DefaultClientID, simpleforce.DefaultAPIVersion)
+	client.SetHttpClient(recorder.Client())
+	exercise(t, client)
+	sid := client.GetSid()
+	if err = recorder.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{sid, url.QueryEscape(sid), "s3cret", "TOKEN123", host} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q", secret)
+		}
+	}
+	if len(recorder.Interactions()) != 9 {
+		t.Errorf("unexpected %d interactions", len(recorder.Interactions()))
+	}
+
+	// Replay it without the server.
+	recorder, err = NewRecorder(path, ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.ScrubString(host, "example.my.salesforce.com")
+	client = simpleforce.NewClient(server.URL, simpleforce.DefaultClientID, simpleforce.DefaultAPIVersion)
+	client.SetHttpClient(recorder.Client())
+	exercise(t, client)
+
+	// Negative: requests that were not recorded, or were already replayed, fail.
+	if _, err = client.Query("SELECT Id, Name FROM Account"); err == nil {
+		t.Fail()
+	}
+	if _, err = NewRecorder(filepath.Join(t.TempDir(), "missing.json"), ModeReplay, nil); err == nil {
+		t.Fail()
+	}
+}
+
+func TestRecorder_scrub(t *testing.T) {
+	recorder := &Recorder{}
+	cases := map[string]string{
+		`<sessionId>00D000000000001!AQ.x</sessionId>`:                    `<sessionId>SCRUBBED</sessionId>`,
+		`<n1:password>pass</n1:password>`:                                `<n1:password>SCRUBBED</n1:password>`,
+		`{"access_token": "abc","instance_url":"x"}`:                     `{"access_token": "SCRUBBED","instance_url":"x"}`,
+		`grant_type=password&password=p&refresh_token=r&client_id=id`:    `grant_type=password&password=SCRUBBED&refresh_token=SCRUBBED&client_id=id`,
+		`{"refresh_token":"r","id_token":"eyJ.x.y","scope":"api"}`:       `{"refresh_token":"SCRUBBED","id_token":"SCRUBBED","scope":"api"}`,
+		`grant_type=refresh_token&refresh_token=r&id_token=eyJ.x.y`:      `grant_type=refresh_token&refresh_token=SCRUBBED&id_token=SCRUBBED`,
+		`/secur/frontdoor.jsp?sid=00D000000000001!AQ.x&retURL=/home`:     `/secur/frontdoor.jsp?sid=SCRUBBED&retURL=/home`,
+		`[{"errorCode":"NOT_FOUND","message":"The requested resource"}]`: `[{"errorCode":"NOT_FOUND","message":"The requested resource"}]`,
+	}
+	for in, expected := range cases {
+		if out := recorder.scrub(in); out != expected {
+			t.Errorf("expected %s, got %s", expected, out)
+		}
+	}
+}
@@ -0,0 +1,799 @@
+// Package simpleforcetest provides an in-process fake of the Salesforce APIs used by simpleforce.Client, so that code
+// built on the client can be tested offline.
+//
+// The fake implements the SOAP login and logout calls, the OAuth token and revoke endpoints, the API versions list,
+// SOQL queries with nextRecordsUrl paging, sobject create, retrieve, update and delete, describe, tooling
+// executeAnonymous and metadata deployRequest, backed by an in-memory record store. Deleted records stay in the store
+// with IsDeleted set, and are only returned by queryAll:
+//
+//	server := simpleforcetest.NewServer()
+//	defer server.Close()
+//	id := server.Insert("Account", map[string]interface{}{"Name": "Acme"})
+//
+//	client := simpleforce.NewClient(server.URL, simpleforce.DefaultClientID, simpleforce.DefaultAPIVersion)
+//	err := client.LoginPassword(simpleforcetest.Username, simpleforcetest.Password, "")
+package simpleforcetest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credentials accepted by a Server unless others are set with SetCredentials.
+const (
+	Username = "user@example.com"
+	Password = "password"
+	OrgID    = "00D000000000001AAA"
+	UserID   = "005000000000001AAA"
+)
+
+// DefaultPageSize is the number of records returned per query page unless the request asks for a smaller batch
+// size with the Sforce-Query-Options header.
+const DefaultPageSize = 2000
+
+// Server is a fake Salesforce org served over HTTP. Its URL is used both as the login URL and as the instance URL of
+// the sessions it issues. A Server is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	username string
+	password string
+	sessions map[string]bool
+	refresh  map[string]bool
+	serial   int
+	pageSize int
+
+	types   map[string]string    // type name by lower-case name, in the case first used.
+	records map[string][]*record // records by lower-case type name, in insertion order.
+	cursors map[string]*cursor   // remaining pages of queries by locator.
+
+	apex        []string
+	apexHandler func(body string) error
+	deploys     map[string]*deployment
+	deployOrder []string
+	deployFunc  func(zip []byte) error
+}
+
+// record is a stored sobject.
+type record struct {
+	typ    string
+	id     string
+	fields map[string]interface{}
+}
+
+func (rec *record) deleted() bool {
+	deleted, _ := rec.fields["IsDeleted"].(bool)
+	return deleted
+}
+
+// cursor holds the records of a query that were not returned yet.
+type cursor struct {
+	records []map[string]interface{}
+	total   int
+	size    int
+	tooling bool
+}
+
+// deployment is a metadata deploy request.
+type deployment struct {
+	zip []byte
+	err error
+}
+
+// NewServer starts a fake Salesforce org accepting the Username and Password credentials. It must be closed with
+// Close once done.
+func NewServer() *Server {
+	server := &Server{
+		username: Username,
+		password: Password,
+		sessions: map[string]bool{},
+		refresh:  map[string]bool{},
+		pageSize: DefaultPageSize,
+		types:    map[string]string{},
+		records:  map[string][]*record{},
+		cursors:  map[string]*cursor{},
+		deploys:  map[string]*deployment{},
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serveHTTP))
+	return server
+}
+
+// SetCredentials sets the username and password accepted by the SOAP login and the OAuth password flow. The
+// password includes the security token, if any.
+func (server *Server) SetCredentials(username, password string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.username, server.password = username, password
+}
+
+// SetPageSize sets the maximum number of records returned per query page.
+func (server *Server) SetPageSize(size int) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.pageSize = size
+}
+
+// NewSession issues a valid session ID without a login, e.g. for simpleforce.Client.SetSidLoc.
+func (server *Server) NewSession() string {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return server.newSessionLocked()
+}
+
+// ExpireSessions invalidates all sessions issued so far. Refresh tokens stay valid.
+func (server *Server) ExpireSessions() {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.sessions = map[string]bool{}
+}
+
+// Insert stores a record of typ with a copy of fields and returns its new ID. Relationship fields can be given as
+// nested maps, e.g. "Owner": map[string]interface{}{"Name": "Jane"}. The record is stored as deleted if IsDeleted is
+// true.
+func (server *Server) Insert(typ string, fields map[string]interface{}) string {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return server.insertLocked(typ, fields)
+}
+
+// Record returns a copy of the fields of a stored record, and whether it exists and is not deleted.
+func (server *Server) Record(typ, id string) (map[string]interface{}, bool) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	rec := server.findLocked(typ, id)
+	if rec == nil {
+		return nil, false
+	}
+	return copyFields(rec.fields), true
+}
+
+// Records returns copies of the stored records of typ which are not deleted, in insertion order.
+func (server *Server) Records(typ string) []map[string]interface{} {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	var out []map[string]interface{}
+	for _, rec := range server.records[strings.ToLower(typ)] {
+		if rec.deleted() {
+			continue
+		}
+		out = append(out, copyFields(rec.fields))
+	}
+	return out
+}
+
+// HandleExecuteAnonymous sets the function called with the Apex code run by executeAnonymous. An error returned by fn
+// is reported as the exception of the execution. By default, all code runs successfully.
+func (server *Server) HandleExecuteAnonymous(fn func(body string) error) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.apexHandler = fn
+}
+
+// ExecutedApex returns the Apex code run by executeAnonymous, in order.
+func (server *Server) ExecutedApex() []string {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return append([]string(nil), server.apex...)
+}
+
+// HandleDeploy sets the function called with the zip file of every metadata deploy request. An error returned by fn
+// fails the deployment with its message. By default, all deployments succeed.
+func (server *Server) HandleDeploy(fn func(zip []byte) error) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.deployFunc = fn
+}
+
+// Deployments returns the zip files of the metadata deploy requests received, in order.
+func (server *Server) Deployments() [][]byte {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	var out [][]byte
+	for _, id := range server.deployOrder {
+		out = append(out, server.deploys[id].zip)
+	}
+	return out
+}
+
+// dataPath matches the REST API paths, capturing the API version and the resource.
+var dataPath = regexp.MustCompile(`^/services/data/v(\d+\.\d+)/(.*)$`)
+
+func (server *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/services/Soap/u/"):
+		server.serveSOAP(w, r)
+	case path == "/services/oauth2/token":
+		server.serveToken(w, r)
+	case path == "/services/oauth2/revoke":
+		w.WriteHeader(http.StatusOK)
+	case path == "/services/data" || path == "/services/data/":
+		server.serveVersions(w)
+	case dataPath.MatchString(path):
+		if !server.authorized(r) {
+			writeError(w, http.StatusUnauthorized, "INVALID_SESSION_ID", "Session expired or invalid")
+			return
+		}
+		match := dataPath.FindStringSubmatch(path)
+		server.serveData(w, r, match[1], match[2])
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+	}
+}
+
+// serveData serves the REST API resources below /services/data/vXX.X/.
+func (server *Server) serveData(w http.ResponseWriter, r *http.Request, version, resource string) {
+	tooling := strings.HasPrefix(resource, "tooling/")
+	resource = strings.TrimPrefix(resource, "tooling/")
+	parts := strings.Split(strings.TrimSuffix(resource, "/"), "/")
+
+	switch {
+	case parts[0] == "query" && len(parts) == 1 && r.Method == http.MethodGet:
+		server.serveQuery(w, r, version, tooling, false)
+	case parts[0] == "queryAll" && len(parts) == 1 && !tooling && r.Method == http.MethodGet:
+		server.serveQuery(w, r, version, false, true)
+	case parts[0] == "query" && len(parts) == 2 && r.Method == http.MethodGet:
+		server.serveQueryMore(w, version, parts[1])
+	case parts[0] == "sobjects":
+		server.serveSObjects(w, r, version, parts[1:])
+	case parts[0] == "executeAnonymous" && tooling && r.Method == http.MethodGet:
+		server.serveExecuteAnonymous(w, r)
+	case parts[0] == "metadata" && len(parts) >= 2 && parts[1] == "deployRequest":
+		server.serveDeploy(w, r, parts[2:])
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+	}
+}
+
+// authorized reports if the request carries a valid session.
+func (server *Server) authorized(r *http.Request) bool {
+	sid := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return server.sessions[sid]
+}
+
+func (server *Server) serveVersions(w http.ResponseWriter) {
+	var versions []map[string]string
+	for major := 20; major <= 62; major++ {
+		version := fmt.Sprintf("%d.0", major)
+		versions = append(versions, map[string]string{
+			"label":   "v" + version,
+			"url":     "/services/data/v" + version,
+			"version": version,
+		})
+	}
+	writeJSON(w, http.StatusOK, versions)
+}
+
+func (server *Server) serveSOAP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	var envelope struct {
+		SessionID string `xml:"Header>SessionHeader>sessionId"`
+		Login     *struct {
+			Username string `xml:"username"`
+			Password string `xml:"password"`
+		} `xml:"Body>login"`
+		Logout *struct{} `xml:"Body>logout"`
+	}
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		writeFault(w, "INVALID_REQUEST", err.Error())
+		return
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	switch {
+	case envelope.Login != nil:
+		if envelope.Login.Username != server.username || envelope.Login.Password != server.password {
+			writeFault(w, "INVALID_LOGIN", "INVALID_LOGIN: Invalid username, password, security token; or user locked out.")
+			return
+		}
+		sid := server.newSessionLocked()
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns="urn:partner.soap.sforce.com">
+<soapenv:Body><loginResponse><result>
+<serverUrl>%s/services/Soap/u/%s/%s</serverUrl>
+<sessionId>%s</sessionId>
+<userId>%s</userId>
+<userInfo><organizationId>%s</organizationId><userEmail>%s</userEmail><userFullName>Test User</userFullName><userName>%s</userName></userInfo>
+</result></loginResponse></soapenv:Body>
+</soapenv:Envelope>`, server.URL, strings.TrimPrefix(r.URL.Path, "/services/Soap/u/"), OrgID, sid, UserID, OrgID,
+			xmlEscape(server.username), xmlEscape(server.username))
+	case envelope.Logout != nil:
+		if !server.sessions[envelope.SessionID] {
+			writeFault(w, "INVALID_SESSION_ID", "INVALID_SESSION_ID: Invalid Session ID found in SessionHeader")
+			return
+		}
+		delete(server.sessions, envelope.SessionID)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body><logoutResponse/></soapenv:Body></soapenv:Envelope>`)
+	default:
+		writeFault(w, "INVALID_OPERATION", "Unsupported SOAP call")
+	}
+}
+
+// serveToken issues sessions for the password, refresh_token, authorization_code, client_credentials and JWT bearer
+// grants. Only the password and refresh token are checked.
+func (server *Server) serveToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, "invalid_request", err.Error())
+		return
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	refreshToken := ""
+	switch r.PostForm.Get("grant_type") {
+	case "password":
+		if r.PostForm.Get("username") != server.username || r.PostForm.Get("password") != server.password {
+			writeOAuthError(w, "invalid_grant", "authentication failure")
+			return
+		}
+	case "refresh_token":
+		if !server.refresh[r.PostForm.Get("refresh_token")] {
+			writeOAuthError(w, "invalid_grant", "expired access/refresh token")
+			return
+		}
+	case "authorization_code":
+		server.serial++
+		refreshToken = fmt.Sprintf("refresh-%d", server.serial)
+		server.refresh[refreshToken] = true
+	case "client_credentials", "urn:ietf:params:oauth:grant-type:jwt-bearer":
+	default:
+		writeOAuthError(w, "unsupported_grant_type", "grant type not supported")
+		return
+	}
+
+	token := map[string]string{
+		"access_token": server.newSessionLocked(),
+		"instance_url": server.URL,
+		"id":           server.URL + "/id/" + OrgID + "/" + UserID,
+		"token_type":   "Bearer",
+		"issued_at":    strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10),
+	}
+	if refreshToken != "" {
+		token["refresh_token"] = refreshToken
+	}
+	writeJSON(w, http.StatusOK, token)
+}
+
+// serveQuery runs a query, over the deleted records too if all is set.
+func (server *Server) serveQuery(w http.ResponseWriter, r *http.Request, version string, tooling, all bool) {
+	q, err := parseQuery(r.URL.Query().Get("q"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "MALFORMED_QUERY", err.Error())
+		return
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	typ, ok := server.types[strings.ToLower(q.from)]
+	if !ok {
+		typ = q.from
+	}
+	var records []map[string]interface{}
+	for _, rec := range server.records[strings.ToLower(typ)] {
+		if all || !rec.deleted() {
+			records = append(records, rec.fields)
+		}
+	}
+
+	matched := q.run(records)
+	if q.count {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"totalSize": len(matched), "done": true, "records": []interface{}{}})
+		return
+	}
+	attributesOf := func(fields map[string]interface{}) interface{} {
+		if attrs, ok := fields["attributes"]; ok {
+			return attrs
+		}
+		if id, ok := fields["Id"].(string); ok {
+			if rec := server.findByIDLocked(id); rec != nil {
+				return attributes(version, rec.typ, rec.id)
+			}
+		}
+		return nil
+	}
+	var rows []map[string]interface{}
+	for _, fields := range matched {
+		rows = append(rows, q.project(fields, attributesOf))
+	}
+
+	size := server.pageSize
+	if batch := batchSize(r.Header.Get("Sforce-Query-Options")); batch > 0 && batch < size {
+		size = batch
+	}
+	server.writePageLocked(w, version, &cursor{records: rows, total: len(rows), size: size, tooling: tooling})
+}
+
+func (server *Server) serveQueryMore(w http.ResponseWriter, version, locator string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	c, ok := server.cursors[locator]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "INVALID_QUERY_LOCATOR", "invalid query locator")
+		return
+	}
+	delete(server.cursors, locator)
+	server.writePageLocked(w, version, c)
+}
+
+// writePageLocked writes the next page of a cursor and keeps the remaining records under a new locator.
+func (server *Server) writePageLocked(w http.ResponseWriter, version string, c *cursor) {
+	page := c.records
+	result := map[string]interface{}{"totalSize": c.total, "done": true}
+	if len(page) > c.size {
+		page = c.records[:c.size]
+		server.serial++
+		locator := fmt.Sprintf("01g%015d-%d", server.serial, c.total-len(c.records)+c.size)
+		server.cursors[locator] = &cursor{records: c.records[c.size:], total: c.total, size: c.size, tooling: c.tooling}
+		base := "/services/data/v" + version + "/query/"
+		if c.tooling {
+			base = "/services/data/v" + version + "/tooling/query/"
+		}
+		result["done"] = false
+		result["nextRecordsUrl"] = base + locator
+	}
+	if page == nil {
+		page = []map[string]interface{}{}
+	}
+	result["records"] = page
+	writeJSON(w, http.StatusOK, result)
+}
+
+// serveSObjects serves the global describe, the describe of a type, and the CRUD of its records.
+func (server *Server) serveSObjects(w http.ResponseWriter, r *http.Request, version string, parts []string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	if len(parts) == 0 || parts[0] == "" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "HTTP Method '"+r.Method+"' not allowed")
+			return
+		}
+		var names []string
+		for _, typ := range server.types {
+			names = append(names, typ)
+		}
+		sort.Strings(names)
+		sobjects := []map[string]interface{}{}
+		for _, typ := range names {
+			sobjects = append(sobjects, map[string]interface{}{"name": typ, "label": typ, "queryable": true})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"encoding": "UTF-8", "maxBatchSize": 200, "sobjects": sobjects})
+		return
+	}
+
+	typ := parts[0]
+	if known, ok := server.types[strings.ToLower(typ)]; ok {
+		typ = known
+	}
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		var fields map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+			writeError(w, http.StatusBadRequest, "JSON_PARSER_ERROR", err.Error())
+			return
+		}
+		delete(fields, "attributes")
+		id := server.insertLocked(typ, fields)
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"id": id, "success": true, "errors": []interface{}{}})
+	case len(parts) == 2 && parts[1] == "describe" && r.Method == http.MethodGet:
+		server.writeDescribeLocked(w, typ)
+	case len(parts) == 2:
+		rec := server.findLocked(typ, parts[1])
+		if rec == nil {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			out := copyFields(rec.fields)
+			out["attributes"] = attributes(version, rec.typ, rec.id)
+			writeJSON(w, http.StatusOK, out)
+		case http.MethodPatch:
+			var fields map[string]interface{}
+			if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+				writeError(w, http.StatusBadRequest, "JSON_PARSER_ERROR", err.Error())
+				return
+			}
+			delete(fields, "attributes")
+			delete(fields, "Id")
+			for key, value := range fields {
+				storedKey, _ := lookupKey(rec.fields, key)
+				rec.fields[storedKey] = value
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			server.deleteLocked(rec)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "HTTP Method '"+r.Method+"' not allowed")
+		}
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+	}
+}
+
+// writeDescribeLocked describes a type from the fields of its stored records.
+func (server *Server) writeDescribeLocked(w http.ResponseWriter, typ string) {
+	records, ok := server.records[strings.ToLower(typ)]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+		return
+	}
+	names := map[string]bool{"Id": true}
+	fields := []map[string]interface{}{{"name": "Id", "type": "id", "label": "ID"}}
+	for _, rec := range records {
+		for name, value := range rec.fields {
+			if names[name] {
+				continue
+			}
+			names[name] = true
+			fields = append(fields, map[string]interface{}{"name": name, "type": fieldType(value), "label": name})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"name":       typ,
+		"label":      typ,
+		"keyPrefix":  keyPrefix(typ),
+		"queryable":  true,
+		"createable": true,
+		"updateable": true,
+		"deletable":  true,
+		"fields":     fields,
+	})
+}
+
+func (server *Server) serveExecuteAnonymous(w http.ResponseWriter, r *http.Request) {
+	body := r.URL.Query().Get("anonymousBody")
+
+	server.mu.Lock()
+	server.apex = append(server.apex, body)
+	handler := server.apexHandler
+	server.mu.Unlock()
+
+	result := map[string]interface{}{
+		"line": -1, "column": -1, "compiled": true, "success": true,
+		"compileProblem": nil, "exceptionMessage": nil, "exceptionStackTrace": nil,
+	}
+	if handler != nil {
+		if err := handler(body); err != nil {
+			result["success"] = false
+			result["exceptionMessage"] = err.Error()
+			result["exceptionStackTrace"] = "AnonymousBlock: line 1, column 1"
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// serveDeploy accepts deploy requests, which complete on the first status poll.
+func (server *Server) serveDeploy(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		zip, err := deployZip(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_MULTIPART_REQUEST", err.Error())
+			return
+		}
+
+		server.mu.Lock()
+		server.serial++
+		id := fmt.Sprintf("0Af%012dAAA", server.serial)
+		server.deploys[id] = &deployment{zip: zip}
+		server.deployOrder = append(server.deployOrder, id)
+		deploy := server.deployFunc
+		server.mu.Unlock()
+
+		if deploy != nil {
+			err = deploy(zip)
+			server.mu.Lock()
+			server.deploys[id].err = err
+			server.mu.Unlock()
+		}
+		writeJSON(w, http.StatusCreated, map[string]interface{}{
+			"id":           id,
+			"deployResult": map[string]interface{}{"id": id, "done": false, "status": "Pending", "success": false},
+		})
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		server.mu.Lock()
+		d, ok := server.deploys[parts[0]]
+		var err error
+		if ok {
+			err = d.err
+		}
+		server.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+			return
+		}
+		result := map[string]interface{}{"id": parts[0], "done": true, "status": "Succeeded", "success": true}
+		if err != nil {
+			result["status"] = "Failed"
+			result["success"] = false
+			result["errorStatusCode"] = "DEPLOYMENT_FAILED"
+			result["errorMessage"] = err.Error()
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": parts[0], "deployResult": result})
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "The requested resource does not exist")
+	}
+}
+
+// deployZip extracts the zip file from a multipart deploy request.
+func deployZip(r *http.Request) ([]byte, error) {
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, fmt.Errorf("expected a multipart request")
+	}
+	reader := multipart.NewReader(r.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, fmt.Errorf("missing file part: %v", err)
+		}
+		if part.FormName() == "file" {
+			return ioutil.ReadAll(part)
+		}
+	}
+}
+
+func (server *Server) newSessionLocked() string {
+	server.serial++
+	sid := fmt.Sprintf("%s!AQ%016d", OrgID[:15], server.serial)
+	server.sessions[sid] = true
+	return sid
+}
+
+func (server *Server) insertLocked(typ string, fields map[string]interface{}) string {
+	if known, ok := server.types[strings.ToLower(typ)]; ok {
+		typ = known
+	} else {
+		server.types[strings.ToLower(typ)] = typ
+	}
+	server.serial++
+	id := fmt.Sprintf("%s%012dAAA", keyPrefix(typ), server.serial)
+	rec := &record{typ: typ, id: id, fields: copyFields(fields)}
+	rec.fields["Id"] = id
+	if _, ok := rec.fields["IsDeleted"]; !ok {
+		rec.fields["IsDeleted"] = false
+	}
+	server.records[strings.ToLower(typ)] = append(server.records[strings.ToLower(typ)], rec)
+	return id
+}
+
+func (server *Server) findLocked(typ, id string) *record {
+	for _, rec := range server.records[strings.ToLower(typ)] {
+		if rec.deleted() {
+			continue
+		}
+		// 15 and 18 character IDs identify the same record.
+		if rec.id == id || (len(id) == 15 && rec.id[:15] == id) {
+			return rec
+		}
+	}
+	return nil
+}
+
+// findByIDLocked finds a record of any type.
+func (server *Server) findByIDLocked(id string) *record {
+	for _, records := range server.records {
+		for _, rec := range records {
+			if rec.id == id {
+				return rec
+			}
+		}
+	}
+	return nil
+}
+
+// deleteLocked moves a record to the recycle bin.
+func (server *Server) deleteLocked(rec *record) {
+	rec.fields["IsDeleted"] = true
+}
+
+// keyPrefixes are the ID prefixes of common standard objects. Other types use a custom object prefix.
+var keyPrefixes = map[string]string{
+	"account":          "001",
+	"contact":          "003",
+	"user":             "005",
+	"opportunity":      "006",
+	"lead":             "00Q",
+	"case":             "500",
+	"contentversion":   "068",
+	"scratchorginfo":   "2SR",
+	"activescratchorg": "2AS",
+}
+
+func keyPrefix(typ string) string {
+	if prefix, ok := keyPrefixes[strings.ToLower(typ)]; ok {
+		return prefix
+	}
+	return "a00"
+}
+
+func fieldType(value interface{}) string {
+	switch value.(type) {
+	case bool:
+		return "boolean"
+	case float64, int, int64:
+		return "double"
+	case map[string]interface{}:
+		return "reference"
+	}
+	return "string"
+}
+
+func attributes(version, typ, id string) map[string]interface{} {
+	return map[string]interface{}{
+		"type": typ,
+		"url":  "/services/data/v" + version + "/sobjects/" + typ + "/" + id,
+	}
+}
+
+// batchSize parses the batch size of a Sforce-Query-Options header, 0 if none.
+func batchSize(options string) int {
+	for _, option := range strings.Split(options, ",") {
+		option = strings.TrimSpace(option)
+		if strings.HasPrefix(option, "batchSize=") {
+			size, _ := strconv.Atoi(strings.TrimPrefix(option, "batchSize="))
+			return size
+		}
+	}
+	return 0
+}
+
+func copyFields(fields map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		out[key] = value
+	}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error in the format of the REST API.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, []map[string]string{{"errorCode": code, "message": message}})
+}
+
+func writeOAuthError(w http.ResponseWriter, code, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": description})
+}
+
+// writeFault writes a SOAP fault.
+func writeFault(w http.ResponseWriter, code, message string) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:sf="urn:fault.partner.soap.sforce.com">
+<soapenv:Body><soapenv:Fault><faultcode>sf:%s</faultcode><faultstring>%s</faultstring></soapenv:Fault></soapenv:Body>
+</soapenv:Envelope>`, code, xmlEscape(message))
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,198 @@
+package simpleforcetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/veloceapps/simpleforce"
+)
+
+func login(t *testing.T, server *Server) *simpleforce.Client {
+	client := simpleforce.NewClient(server.URL, simpleforce.DefaultClientID, simpleforce.DefaultAPIVersion)
+	if err := client.LoginPassword(Username, Password, ""); err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestServer_Login(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	client := login(t, server)
+	if client.GetLoc() != server.URL || client.GetSid() == "" {
+		t.Errorf("unexpected session %q %q", client.GetLoc(), client.GetSid())
+	}
+	if err := client.Logout(); err != nil {
+		t.Error(err)
+	}
+
+	// OAuth sessions are refreshed once expired.
+	client = simpleforce.NewClient(server.URL, simpleforce.DefaultClientID, simpleforce.DefaultAPIVersion)
+	if err := client.LoginWithAuthCode(server.URL, "code"); err != nil {
+		t.Fatal(err)
+	}
+	sid := client.GetSid()
+	server.ExpireSessions()
+	if _, err := client.Query("SELECT Id FROM Account"); err != nil || client.GetSid() == sid {
+		t.Errorf("session not refreshed: %v", err)
+	}
+
+	// Negative: wrong credentials and expired sessions are rejected.
+	server.SetCredentials("admin@example.com", "secret")
+	client = simpleforce.NewClient(server.URL, simpleforce.DefaultClientID, simpleforce.DefaultAPIVersion)
+	if err := client.LoginPassword(Username, Password, ""); !errors.Is(err, simpleforce.ErrInvalidLogin) {
+		t.Errorf("unexpected %v", err)
+	}
+	client.SetSidLoc(server.NewSession(), server.URL)
+	server.ExpireSessions()
+	if _, err := client.Query("SELECT Id FROM Account"); !errors.Is(err, simpleforce.ErrInvalidSessionID) {
+		t.Errorf("unexpected %v", err)
+	}
+}
+
+func TestServer_Query(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	ownerID := server.Insert("User", map[string]interface{}{"Name": "Jane"})
+	for i := 0; i < 5; i++ {
+		server.Insert("Account", map[string]interface{}{
+			"Name":  fmt.Sprintf("Account %d", i),
+			"Owner": map[string]interface{}{"Id": ownerID, "Name": "Jane"},
+		})
+	}
+	client := login(t, server)
+
+	// Pages follow nextRecordsUrl, with the batch size requested.
+	ctx := simpleforce.WithQueryBatchSize(context.Background(), 2)
+	q := "SELECT Id, Name, Owner.Name FROM Account WHERE Name != 'Account 0' ORDER BY Name DESC"
+	var names []string
+	for {
+		result, err := client.QueryContext(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.TotalSize != 4 || len(result.Records) > 2 {
+			t.Fatalf("unexpected result %+v", result)
+		}
+		for _, record := range result.Records {
+			names = append(names, record.StringField("Name"))
+			if record.Type() != "Account" || record.SObjectField("User", "Owner").StringField("Name") != "Jane" {
+				t.Errorf("unexpected record %v", record)
+			}
+		}
+		if result.Done {
+			break
+		}
+		q = result.NextRecordsURL
+	}
+	if fmt.Sprint(names) != "[Account 4 Account 3 Account 2 Account 1]" {
+		t.Errorf("unexpected names %v", names)
+	}
+
+	result, err := client.Query("SELECT COUNT() FROM Account")
+	if err != nil || result.TotalSize != 5 || len(result.Records) != 0 {
+		t.Errorf("unexpected count %+v %v", result, err)
+	}
+
+	// Negative: malformed queries are rejected.
+	if _, err = client.Query("SELECT FROM Account"); !errors.Is(err, simpleforce.ErrMalformedQuery) {
+		t.Errorf("unexpected %v", err)
+	}
+}
+
+func TestServer_CRUD(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := login(t, server)
+	ctx := context.Background()
+
+	created, err := client.SObject("Case").Set("Subject", "Broken").CreateContext(ctx)
+	if err != nil || created.ID() == "" {
+		t.Fatalf("unexpected %v", err)
+	}
+	id := created.ID()
+
+	got, err := client.SObject("Case").GetContext(ctx, id)
+	if err != nil || got.StringField("Subject") != "Broken" || got.Type() != "Case" {
+		t.Fatalf("unexpected %v %v", got, err)
+	}
+
+	if _, err = client.SObject("Case").Set("Id", id).Set("Subject", "Fixed").UpdateContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if fields, ok := server.Record("Case", id); !ok || fields["Subject"] != "Fixed" {
+		t.Errorf("unexpected record %v", fields)
+	}
+
+	meta, err := client.SObject("Case").DescribeContext(ctx)
+	if err != nil || (*meta)["name"] != "Case" {
+		t.Errorf("unexpected describe %v %v", meta, err)
+	}
+	global, err := client.DescribeGlobal()
+	if err != nil || len((*global)["sobjects"].([]interface{})) != 1 {
+		t.Errorf("unexpected global describe %v %v", global, err)
+	}
+
+	if err = client.SObject("Case").Set("Id", id).Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.Records("Case")) != 0 {
+		t.Fail()
+	}
+
+	// Deleted records stay in the recycle bin.
+	result, err := client.QueryAll("SELECT Id, IsDeleted FROM Case")
+	if err != nil || result.TotalSize != 1 || result.Records[0].InterfaceField("IsDeleted") != true {
+		t.Errorf("unexpected %+v %v", result, err)
+	}
+
+	// Negative: missing records are not found.
+	if _, err = client.SObject("Case").GetContext(ctx, id); !errors.Is(err, simpleforce.ErrNotFound) {
+		t.Errorf("unexpected %v", err)
+	}
+}
+
+func TestServer_ExecuteAnonymous(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := login(t, server)
+
+	if _, err := client.ExecuteAnonymous("System.debug('ok');"); err != nil {
+		t.Error(err)
+	}
+	server.HandleExecuteAnonymous(func(body string) error {
+		return errors.New("System.NullPointerException")
+	})
+	if _, err := client.ExecuteAnonymous("Integer i = null; i++;"); err == nil {
+		t.Fail()
+	}
+	if apex := server.ExecutedApex(); len(apex) != 2 || apex[0] != "System.debug('ok');" {
+		t.Errorf("unexpected apex %q", apex)
+	}
+}
+
+func TestServer_MetaDeploy(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := login(t, server)
+
+	result, err := client.MetaDeploy([]byte("PK zip"), "NoTestRun")
+	if err != nil || !result.Success {
+		t.Fatalf("unexpected %+v %v", result, err)
+	}
+	if deployments := server.Deployments(); len(deployments) != 1 || string(deployments[0]) != "PK zip" {
+		t.Errorf("unexpected deployments %q", deployments)
+	}
+
+	// Negative: failed deployments are reported.
+	server.HandleDeploy(func(zip []byte) error {
+		return errors.New("Missing package.xml")
+	})
+	result, err = client.MetaDeploy([]byte("PK zip"), "NoTestRun")
+	if err == nil || result.Success || result.ErrorMessage != "Missing package.xml" {
+		t.Errorf("unexpected %+v %v", result, err)
+	}
+}
@@ -0,0 +1,578 @@
+package simpleforcetest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// query is a parsed SOQL statement. The fake understands a subset of SOQL:
+//
+//	SELECT field, Parent.Field, FIELDS(ALL), COUNT() FROM Type
+//	[WHERE field op value [AND|OR ...]] [ORDER BY field [ASC|DESC]] [LIMIT n] [OFFSET n]
+//
+// where op is one of =, !=, <>, <, <=, >, >=, LIKE, IN and NOT IN, and conditions may be grouped with parentheses and
+// negated with NOT. Subqueries, aggregates other than COUNT() and date functions are not supported.
+type query struct {
+	fields  []string
+	all     bool // FIELDS(ALL), FIELDS(STANDARD) or FIELDS(CUSTOM), which all select every stored field.
+	count   bool
+	from    string
+	where   condition
+	orderBy string
+	desc    bool
+	limit   int // -1 if unlimited.
+	offset  int
+}
+
+// condition filters the records of a query.
+type condition func(fields map[string]interface{}) bool
+
+// parseQuery parses a SOQL statement.
+func parseQuery(soql string) (*query, error) {
+	tokens, err := tokenize(soql)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	q := &query{limit: -1}
+
+	if err = p.expect("SELECT"); err != nil {
+		return nil, err
+	}
+	for {
+		field := p.next()
+		if field.kind != tokenWord {
+			return nil, fmt.Errorf("unexpected token %q in field list", field.text)
+		}
+		if strings.EqualFold(field.text, "COUNT") && p.peek().text == "(" {
+			p.next()
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			q.count = true
+		} else if strings.EqualFold(field.text, "FIELDS") && p.peek().text == "(" {
+			p.next()
+			set := p.next()
+			if !strings.EqualFold(set.text, "ALL") && !strings.EqualFold(set.text, "STANDARD") &&
+				!strings.EqualFold(set.text, "CUSTOM") {
+				return nil, fmt.Errorf("unexpected field set %q", set.text)
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			q.all = true
+		} else {
+			q.fields = append(q.fields, field.text)
+		}
+		if p.peek().text != "," {
+			break
+		}
+		p.next()
+	}
+
+	if err = p.expect("FROM"); err != nil {
+		return nil, err
+	}
+	from := p.next()
+	if from.kind != tokenWord {
+		return nil, fmt.Errorf("unexpected token %q after FROM", from.text)
+	}
+	q.from = from.text
+
+	if p.acceptKeyword("WHERE") {
+		if q.where, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("ORDER") {
+		if err = p.expect("BY"); err != nil {
+			return nil, err
+		}
+		field := p.next()
+		if field.kind != tokenWord {
+			return nil, fmt.Errorf("unexpected token %q after ORDER BY", field.text)
+		}
+		q.orderBy = field.text
+		if p.acceptKeyword("DESC") {
+			q.desc = true
+		} else {
+			p.acceptKeyword("ASC")
+		}
+	}
+	if p.acceptKeyword("LIMIT") {
+		if q.limit, err = p.parseInt(); err != nil {
+			return nil, err
+		}
+	}
+	if p.acceptKeyword("OFFSET") {
+		if q.offset, err = p.parseInt(); err != nil {
+			return nil, err
+		}
+	}
+	if token := p.next(); token.kind != tokenEnd {
+		return nil, fmt.Errorf("unexpected token %q", token.text)
+	}
+	return q, nil
+}
+
+// run selects the records matching the query, in order.
+func (q *query) run(records []map[string]interface{}) []map[string]interface{} {
+	var matched []map[string]interface{}
+	for _, fields := range records {
+		if q.where == nil || q.where(fields) {
+			matched = append(matched, fields)
+		}
+	}
+	if q.orderBy != "" {
+		sort.SliceStable(matched, func(i, j int) bool {
+			c := compare(lookup(matched[i], q.orderBy), lookup(matched[j], q.orderBy))
+			if q.desc {
+				return c > 0
+			}
+			return c < 0
+		})
+	}
+	if q.offset >= len(matched) {
+		return nil
+	}
+	matched = matched[q.offset:]
+	if q.limit >= 0 && q.limit < len(matched) {
+		matched = matched[:q.limit]
+	}
+	return matched
+}
+
+// project returns the selected fields of a record. Relationship fields such as Account.Name are nested under the
+// relationship name, like the REST API does, with the attributes returned by attributesOf for the related record.
+func (q *query) project(fields map[string]interface{}, attributesOf func(fields map[string]interface{}) interface{}) map[string]interface{} {
+	out := map[string]interface{}{"attributes": attributesOf(fields)}
+	if q.all {
+		for key, value := range fields {
+			if _, nested := value.(map[string]interface{}); !nested {
+				out[key] = value
+			}
+		}
+	}
+	for _, path := range q.fields {
+		parts := strings.Split(path, ".")
+		target := out
+		current := fields
+		for i, part := range parts {
+			key, value := lookupKey(current, part)
+			if i == len(parts)-1 {
+				target[key] = value
+				break
+			}
+			nested, ok := value.(map[string]interface{})
+			if !ok {
+				target[key] = nil
+				break
+			}
+			child, ok := target[key].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{"attributes": attributesOf(nested)}
+				target[key] = child
+			}
+			target, current = child, nested
+		}
+	}
+	return out
+}
+
+// lookup returns the value of a field or dotted relationship path, nil if it is not set.
+func lookup(fields map[string]interface{}, path string) interface{} {
+	var value interface{} = fields
+	for _, part := range strings.Split(path, ".") {
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		_, value = lookupKey(nested, part)
+	}
+	return value
+}
+
+// lookupKey finds a field by case-insensitive name and returns its stored name and value.
+func lookupKey(fields map[string]interface{}, name string) (string, interface{}) {
+	if value, ok := fields[name]; ok {
+		return name, value
+	}
+	for key, value := range fields {
+		if strings.EqualFold(key, name) {
+			return key, value
+		}
+	}
+	return name, nil
+}
+
+// compare orders two field values: nulls first, then numbers, booleans and strings compared case-insensitively.
+func compare(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(strings.ToLower(fmt.Sprint(a)), strings.ToLower(fmt.Sprint(b)))
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// likePattern converts a LIKE pattern into a regular expression: % matches any sequence, _ any character, and a
+// backslash escapes the next character.
+func likePattern(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("(?is)^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			b.WriteString(".*")
+		case r == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenWord
+	tokenString
+	tokenSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// tokenize splits a SOQL statement into words, string literals and symbols.
+func tokenize(soql string) ([]token, error) {
+	var tokens []token
+	runes := []rune(soql)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'':
+			var b strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '\''; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					switch runes[i] {
+					case 'n':
+						b.WriteRune('\n')
+					case 'r':
+						b.WriteRune('\r')
+					case 't':
+						b.WriteRune('\t')
+					case '%', '_':
+						// Kept escaped for LIKE patterns.
+						b.WriteRune('\\')
+						b.WriteRune(runes[i])
+					default:
+						b.WriteRune(runes[i])
+					}
+					continue
+				}
+				b.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string literal")
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: b.String()})
+		case strings.ContainsRune("(),", r):
+			tokens = append(tokens, token{kind: tokenSymbol, text: string(r)})
+			i++
+		case strings.ContainsRune("=!<>", r):
+			j := i + 1
+			if j < len(runes) && strings.ContainsRune("=>", runes[j]) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenSymbol, text: string(runes[i:j])})
+			i = j
+		case isWordRune(r):
+			j := i
+			for j < len(runes) && isWordRune(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: string(runes[i:j])})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q", r)
+		}
+	}
+	return tokens, nil
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.:-+", r)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	if p.pos >= len(p.tokens) {
+		return token{kind: tokenEnd}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return t
+}
+
+// acceptKeyword consumes the next token if it is the keyword.
+func (p *parser) acceptKeyword(keyword string) bool {
+	if t := p.peek(); t.kind == tokenWord && strings.EqualFold(t.text, keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expect consumes the next token, which must be the keyword or symbol.
+func (p *parser) expect(text string) error {
+	if t := p.next(); t.kind == tokenString || !strings.EqualFold(t.text, text) {
+		return fmt.Errorf("expected %s, got %q", text, t.text)
+	}
+	return nil
+}
+
+func (p *parser) parseInt() (int, error) {
+	t := p.next()
+	n, err := strconv.Atoi(t.text)
+	if t.kind != tokenWord || err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid number %q", t.text)
+	}
+	return n, nil
+}
+
+func (p *parser) parseOr() (condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(fields map[string]interface{}) bool { return l(fields) || right(fields) }
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (condition, error) {
+	left, err := p.parsePredicate()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		right, err := p.parsePredicate()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(fields map[string]interface{}) bool { return l(fields) && right(fields) }
+	}
+	return left, nil
+}
+
+func (p *parser) parsePredicate() (condition, error) {
+	if p.acceptKeyword("NOT") {
+		inner, err := p.parsePredicate()
+		if err != nil {
+			return nil, err
+		}
+		return func(fields map[string]interface{}) bool { return !inner(fields) }, nil
+	}
+	if p.peek().text == "(" {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	}
+
+	field := p.next()
+	if field.kind != tokenWord {
+		return nil, fmt.Errorf("unexpected token %q in condition", field.text)
+	}
+	path := field.text
+
+	negate := p.acceptKeyword("NOT")
+	if p.acceptKeyword("IN") {
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return func(fields map[string]interface{}) bool {
+			value := lookup(fields, path)
+			for _, candidate := range values {
+				if equal(value, candidate) {
+					return !negate
+				}
+			}
+			return negate
+		}, nil
+	}
+	if negate {
+		return nil, fmt.Errorf("expected IN after NOT")
+	}
+
+	op := p.next()
+	if op.kind != tokenSymbol && !strings.EqualFold(op.text, "LIKE") {
+		return nil, fmt.Errorf("unexpected operator %q", op.text)
+	}
+	if strings.EqualFold(op.text, "LIKE") {
+		// The pattern is kept as written, with its escaped wildcards.
+		pattern := p.next()
+		if pattern.kind != tokenString {
+			return nil, fmt.Errorf("LIKE requires a string literal")
+		}
+		re, err := likePattern(pattern.text)
+		if err != nil {
+			return nil, err
+		}
+		return func(fields map[string]interface{}) bool {
+			value, ok := lookup(fields, path).(string)
+			return ok && re.MatchString(value)
+		}, nil
+	}
+	literal, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToUpper(op.text) {
+	case "=":
+		return func(fields map[string]interface{}) bool { return equal(lookup(fields, path), literal) }, nil
+	case "!=", "<>":
+		return func(fields map[string]interface{}) bool { return !equal(lookup(fields, path), literal) }, nil
+	case "<", "<=", ">", ">=":
+		text := op.text
+		return func(fields map[string]interface{}) bool {
+			value := lookup(fields, path)
+			if value == nil || literal == nil {
+				return false
+			}
+			c := compare(value, literal)
+			switch text {
+			case "<":
+				return c < 0
+			case "<=":
+				return c <= 0
+			case ">":
+				return c > 0
+			}
+			return c >= 0
+		}, nil
+	}
+	return nil, fmt.Errorf("unexpected operator %q", op.text)
+}
+
+func (p *parser) parseList() ([]interface{}, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var values []interface{}
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if p.peek().text != "," {
+			break
+		}
+		p.next()
+	}
+	return values, p.expect(")")
+}
+
+// parseValue parses a literal: a string, a number, true, false, null, or an unquoted date or datetime kept as a
+// string.
+func (p *parser) parseValue() (interface{}, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return strings.NewReplacer(`\%`, "%", `\_`, "_").Replace(t.text), nil
+	case tokenWord:
+		switch strings.ToLower(t.text) {
+		case "null":
+			return nil, nil
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		if n, err := strconv.ParseFloat(t.text, 64); err == nil {
+			return n, nil
+		}
+		return t.text, nil
+	}
+	return nil, fmt.Errorf("unexpected token %q, expected a value", t.text)
+}
+
+// equal compares two field values, strings case-insensitively.
+func equal(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if x, ok := a.(bool); ok {
+		y, ok := b.(bool)
+		return ok && x == y
+	}
+	return compare(a, b) == 0
+}
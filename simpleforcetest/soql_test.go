@@ -0,0 +1,75 @@
+package simpleforcetest
+
+import (
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	records := []map[string]interface{}{
+		{"Id": "1", "Name": "O'Brien", "Amount": 10.0, "Active": true},
+		{"Id": "2", "Name": "50% off", "Amount": 20.0, "Active": false},
+		{"Id": "3", "Name": "Acme", "Amount": nil, "Active": true, "Owner": map[string]interface{}{"Name": "Jane"}},
+	}
+
+	cases := map[string][]string{
+		"SELECT Id FROM Account":                                           {"1", "2", "3"},
+		"SELECT Id FROM Account WHERE Name = 'o\\'brien'":                  {"1"},
+		"SELECT Id FROM Account WHERE Name LIKE '%\\%%'":                   {"2"},
+		"SELECT Id FROM Account WHERE Name LIKE 'a_me'":                    {"3"},
+		"SELECT Id FROM Account WHERE Amount > 10 OR Amount = null":        {"2", "3"},
+		"SELECT Id FROM Account WHERE Active = true AND NOT (Amount < 15)": {"3"},
+		"SELECT Id FROM Account WHERE Id IN ('1', '3')":                    {"1", "3"},
+		"SELECT Id FROM Account WHERE Id NOT IN ('1', '3')":                {"2"},
+		"SELECT Id FROM Account WHERE Owner.Name = 'Jane'":                 {"3"},
+		"SELECT Id FROM Account ORDER BY Amount DESC LIMIT 2":              {"2", "1"},
+		"select Id from Account order by Name limit 1 offset 1":            {"3"},
+	}
+	for soql, expected := range cases {
+		q, err := parseQuery(soql)
+		if err != nil {
+			t.Errorf("%s: %v", soql, err)
+			continue
+		}
+		var ids []string
+		for _, fields := range q.run(records) {
+			ids = append(ids, fields["Id"].(string))
+		}
+		if len(ids) != len(expected) {
+			t.Errorf("%s: expected %v, got %v", soql, expected, ids)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != expected[i] {
+				t.Errorf("%s: expected %v, got %v", soql, expected, ids)
+				break
+			}
+		}
+	}
+
+	// Relationship fields are nested in the projection.
+	q, _ := parseQuery("SELECT Owner.Name, name FROM Account")
+	out := q.project(records[2], func(map[string]interface{}) interface{} { return nil })
+	if out["Owner"].(map[string]interface{})["Name"] != "Jane" || out["Name"] != "Acme" {
+		t.Errorf("unexpected projection %v", out)
+	}
+
+	q, _ = parseQuery("SELECT FIELDS(ALL) FROM Account")
+	if out = q.project(records[0], func(map[string]interface{}) interface{} { return nil }); len(out) != 5 {
+		t.Errorf("unexpected projection %v", out)
+	}
+
+	// Negative: unsupported or malformed statements are rejected.
+	for _, soql := range []string{
+		"",
+		"SELECT Id Account",
+		"SELECT Id FROM Account WHERE",
+		"SELECT Id FROM Account WHERE Name = 'open",
+		"SELECT Id FROM Account LIMIT -1",
+		"SELECT Id, (SELECT Id FROM Contacts) FROM Account",
+		"SELECT FIELDS(Id) FROM Account",
+	} {
+		if _, err := parseQuery(soql); err == nil {
+			t.Errorf("%q: expected an error", soql)
+		}
+	}
+}
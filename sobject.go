@@ -1,9 +1,8 @@
 package simpleforce
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
-	"log"
 	"net/http"
 	"strings"
 )
@@ -53,22 +52,32 @@ type SObjectAttributes struct {
 // Describe queries the metadata of an SObject using the "describe" API.
 // Ref: https://developer.salesforce.com/docs/atlas.en-us.214.0.api_rest.meta/api_rest/resources_sobject_describe.htm
 func (obj *SObject) Describe() *SObjectMeta {
+	meta, err := obj.DescribeContext(context.Background())
+	if err != nil {
+		obj.client().log(LevelError, "describe failed", "type", obj.Type(), "error", err)
+		return nil
+	}
+	return meta
+}
+
+// DescribeContext is like Describe, the request is bound to ctx and the reason of a failure is returned.
+func (obj *SObject) DescribeContext(ctx context.Context) (*SObjectMeta, error) {
 	if obj.Type() == "" || obj.client() == nil {
 		// Sanity check.
-		return nil
+		return nil, ErrInvalidSObject
 	}
 	url := obj.client().makeURL("sobjects/" + obj.Type() + "/describe")
-	data, err := obj.client().httpRequest(http.MethodGet, url, nil)
+	data, err := obj.client().httpRequest(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
 	var meta SObjectMeta
 	err = json.Unmarshal(data, &meta)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	return &meta
+	return &meta, nil
 }
 
 // Get retrieves all the data fields of an SObject. If id is provided, the SObject with the provided external ID will
@@ -77,9 +86,20 @@ func (obj *SObject) Describe() *SObjectMeta {
 // If query is successful, the SObject is updated in-place and exact same address is returned; otherwise, nil is
 // returned if failed.
 func (obj *SObject) Get(id ...string) *SObject {
+	result, err := obj.GetContext(context.Background(), id...)
+	if err != nil {
+		obj.client().log(LevelError, "get failed", "type", obj.Type(), "error", err)
+		return nil
+	}
+	return result
+}
+
+// GetContext is like Get, the request is bound to ctx and the reason of a failure is returned: ErrMissingID if there
+// is no ID to retrieve, an *APIError with the NOT_FOUND code if the record does not exist.
+func (obj *SObject) GetContext(ctx context.Context, id ...string) (*SObject, error) {
 	if obj.Type() == "" || obj.client() == nil {
 		// Sanity check.
-		return nil
+		return nil, ErrInvalidSObject
 	}
 
 	oid := obj.ID()
@@ -87,24 +107,21 @@ func (obj *SObject) Get(id ...string) *SObject {
 		oid = id[0]
 	}
 	if oid == "" {
-		log.Println(logPrefix, "object id not found.")
-		return nil
+		return nil, ErrMissingID
 	}
 
 	url := obj.client().makeURL("sobjects/" + obj.Type() + "/" + oid)
-	data, err := obj.client().httpRequest(http.MethodGet, url, nil)
+	data, err := obj.client().httpRequest(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		log.Println(logPrefix, "http request failed,", err)
-		return nil
+		return nil, err
 	}
 
 	err = json.Unmarshal(data, obj)
 	if err != nil {
-		log.Println(logPrefix, "json decode failed,", err)
-		return nil
+		return nil, err
 	}
 
-	return obj
+	return obj, nil
 }
 
 // Create posts the JSON representation of the SObject to salesforce to create the entry.
@@ -112,61 +129,79 @@ func (obj *SObject) Get(id ...string) *SObject {
 // returned for failures.
 // Ref: https://developer.salesforce.com/docs/atlas.en-us.214.0.api_rest.meta/api_rest/dome_sobject_create.htm
 func (obj *SObject) Create() *SObject {
+	result, err := obj.CreateContext(context.Background())
+	if err != nil {
+		obj.client().log(LevelError, "create failed", "type", obj.Type(), "error", err)
+		return nil
+	}
+	return result
+}
+
+// CreateContext is like Create, the request is bound to ctx and the reason of a failure is returned. Errors reported
+// for the record, such as validation rule or duplicate rule failures, are returned as an *APIError.
+func (obj *SObject) CreateContext(ctx context.Context) (*SObject, error) {
 	if obj.Type() == "" || obj.client() == nil {
 		// Sanity check.
-		return nil
+		return nil, ErrInvalidSObject
 	}
 
 	// Make a copy of the incoming SObject, but skip certain metadata fields as they're not understood by salesforce.
 	reqObj := obj.makeCopy()
 	reqData, err := json.Marshal(reqObj)
 	if err != nil {
-		log.Println(logPrefix, "failed to convert sobject to json,", err)
-		return nil
+		return nil, err
 	}
 
 	url := obj.client().makeURL("sobjects/" + obj.Type() + "/")
-	respData, err := obj.client().httpRequest(http.MethodPost, url, bytes.NewReader(reqData))
+	respData, err := obj.client().httpRequest(ctx, http.MethodPost, url, reqData)
 	if err != nil {
-		log.Println(logPrefix, "failed to process http request,", err)
-		return nil
+		return nil, err
 	}
 
-	// Use an anonymous struct to parse the result if any. This might need to be changed if the result should
-	// be returned to the caller in some manner, especially if the client would like to decode the errors.
 	var respVal struct {
-		ID      string `json:"id"`
-		Success bool   `json:"success"`
+		ID      string       `json:"id"`
+		Success bool         `json:"success"`
+		Errors  []ErrorEntry `json:"errors"`
 	}
 	err = json.Unmarshal(respData, &respVal)
 	if err != nil {
-		log.Println(logPrefix, "failed to process response data,", err)
-		return nil
+		return nil, err
 	}
 
 	if !respVal.Success || respVal.ID == "" {
-		log.Println(logPrefix, "unsuccessful")
-		return nil
+		return nil, &APIError{StatusCode: http.StatusCreated, Errors: respVal.Errors, Body: respData}
 	}
 
 	obj.setID(respVal.ID)
-	return obj
+	return obj, nil
 }
 
 // Update updates SObject in place. Upon successful, same SObject is returned for chained access.
 // ID is required.
 func (obj *SObject) Update() *SObject {
-	if obj.Type() == "" || obj.client() == nil || obj.ID() == "" {
-		// Sanity check.
+	result, err := obj.UpdateContext(context.Background())
+	if err != nil {
+		obj.client().log(LevelError, "update failed", "type", obj.Type(), "error", err)
 		return nil
 	}
+	return result
+}
+
+// UpdateContext is like Update, the request is bound to ctx and the reason of a failure is returned.
+func (obj *SObject) UpdateContext(ctx context.Context) (*SObject, error) {
+	if obj.Type() == "" || obj.client() == nil {
+		// Sanity check.
+		return nil, ErrInvalidSObject
+	}
+	if obj.ID() == "" {
+		return nil, ErrMissingID
+	}
 
 	// Make a copy of the incoming SObject, but skip certain metadata fields as they're not understood by salesforce.
 	reqObj := obj.makeCopy()
 	reqData, err := json.Marshal(reqObj)
 	if err != nil {
-		log.Println(logPrefix, "failed to convert sobject to json,", err)
-		return nil
+		return nil, err
 	}
 
 	queryBase := "sobjects/"
@@ -174,19 +209,22 @@ func (obj *SObject) Update() *SObject {
 		queryBase = "tooling/sobjects/"
 	}
 	url := obj.client().makeURL(queryBase + obj.Type() + "/" + obj.ID())
-	respData, err := obj.client().httpRequest(http.MethodPatch, url, bytes.NewReader(reqData))
+	_, err = obj.client().httpRequest(ctx, http.MethodPatch, url, reqData)
 	if err != nil {
-		log.Println(logPrefix, "failed to process http request,", err)
-		return nil
+		return nil, err
 	}
-	log.Println(string(respData))
 
-	return obj
+	return obj, nil
 }
 
 // Delete deletes an SObject record identified by external ID. nil is returned if the operation completes successfully;
 // otherwise an error is returned
 func (obj *SObject) Delete(id ...string) error {
+	return obj.DeleteContext(context.Background(), id...)
+}
+
+// DeleteContext is like Delete, the request is bound to ctx.
+func (obj *SObject) DeleteContext(ctx context.Context, id ...string) error {
 	if obj.Type() == "" || obj.client() == nil {
 		// Sanity check
 		return ErrFailure
@@ -201,8 +239,7 @@ func (obj *SObject) Delete(id ...string) error {
 	}
 
 	url := obj.client().makeURL("sobjects/" + obj.Type() + "/" + obj.ID())
-	log.Println(url)
-	_, err := obj.client().httpRequest(http.MethodDelete, url, nil)
+	_, err := obj.client().httpRequest(ctx, http.MethodDelete, url, nil)
 	if err != nil {
 		return err
 	}
@@ -271,7 +308,7 @@ func (obj *SObject) SObjectField(typeName, key string) *SObject {
 	rIndex := strings.LastIndex(url, "/")
 	if rIndex == -1 || rIndex+1 == len(url) {
 		// hmm... this shouldn't happen, unless the URL is hand crafted.
-		log.Println(logPrefix, "invalid url,", url)
+		obj.client().log(LevelWarn, "invalid url", "url", url)
 		return nil
 	}
 	oid = url[rIndex+1:]
@@ -1,7 +1,14 @@
 package simpleforce
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -246,3 +253,70 @@ func TestSObject_GetUpdate(t *testing.T) {
 	user1 := client.SObject("User").Create()
 	log.Println(user1.ID())
 }
+
+func TestSObject_CRUDContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/sobjects/Case/500000000000001"):
+			fmt.Fprint(w, `{"attributes":{"type":"Case"},"Id":"500000000000001","Subject":"simpleforce"}`)
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/sobjects/Case/describe"):
+			fmt.Fprint(w, `{"name":"Case"}`)
+		case r.Method == http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `[{"errorCode":"NOT_FOUND","message":"The requested resource does not exist"}]`)
+		case r.Method == http.MethodPost:
+			body, _ := ioutil.ReadAll(r.Body)
+			if strings.Contains(string(body), "Subject") {
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"id":"500000000000002","success":true,"errors":[]}`)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id":"","success":false,"errors":[{"statusCode":"REQUIRED_FIELD_MISSING","message":"Required fields are missing: [Subject]","fields":["Subject"]}]}`)
+		case r.Method == http.MethodPatch:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `[{"errorCode":"FIELD_CUSTOM_VALIDATION_EXCEPTION","message":"Subject is too short","fields":["Subject"]}]`)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	client.SetSidLoc("__SESSION__", server.URL)
+	ctx := context.Background()
+
+	if meta, err := client.SObject("Case").DescribeContext(ctx); err != nil || (*meta)["name"] != "Case" {
+		t.Fail()
+	}
+	if obj, err := client.SObject("Case").GetContext(ctx, "500000000000001"); err != nil || obj.StringField("Subject") != "simpleforce" {
+		t.Fail()
+	}
+	if obj, err := client.SObject("Case").Set("Subject", "simpleforce").CreateContext(ctx); err != nil || obj.ID() != "500000000000002" {
+		t.Fail()
+	}
+
+	// Negative: the reason of failures is returned.
+	if _, err := client.SObject("Case").GetContext(ctx, "500000000000009"); !errors.Is(err, ErrNotFound) {
+		t.Fail()
+	}
+	if _, err := client.SObject("Case").GetContext(ctx); err != ErrMissingID {
+		t.Fail()
+	}
+	if _, err := (&SObject{}).GetContext(ctx, "500000000000001"); err != ErrInvalidSObject {
+		t.Fail()
+	}
+	_, err := client.SObject("Case").Set("Origin", "Web").CreateContext(ctx)
+	var apiError *APIError
+	if !errors.As(err, &apiError) || !errors.Is(err, ErrRequiredFieldMissing) || apiError.Errors[0].Fields[0] != "Subject" {
+		t.Fail()
+	}
+	if _, err = client.SObject("Case").Set("Subject", "x").UpdateContext(ctx); err != ErrMissingID {
+		t.Fail()
+	}
+	obj := client.SObject("Case").Set("Id", "500000000000001").Set("Subject", "x")
+	if _, err = obj.UpdateContext(ctx); !errors.Is(err, ErrFieldCustomValidationException) {
+		t.Fail()
+	}
+	if obj.Update() != nil {
+		t.Fail()
+	}
+}
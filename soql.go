@@ -0,0 +1,308 @@
+package simpleforce
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// soqlEscaper escapes the characters with a special meaning in SOQL and Apex string literals.
+var soqlEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `\'`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+	"\b", `\b`,
+	"\f", `\f`,
+)
+
+// EscapeString escapes s for use inside a single-quoted SOQL or Apex string literal, e.g.
+// "WHERE Name = '" + EscapeString(name) + "'".
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.soql_sosl.meta/soql_sosl/sforce_api_calls_soql_select_quotedstringescapes.htm
+func EscapeString(s string) string {
+	return soqlEscaper.Replace(s)
+}
+
+// EscapeLike is like EscapeString, but also escapes the % and _ wildcards for use inside a LIKE pattern, e.g.
+// "WHERE Name LIKE '" + EscapeLike(prefix) + "%'".
+func EscapeLike(s string) string {
+	return strings.NewReplacer("%", `\%`, "_", `\_`).Replace(EscapeString(s))
+}
+
+// Date is a value formatted as a SOQL date literal such as 2024-01-31, for date fields. A time.Time value is formatted
+// as a datetime literal.
+type Date time.Time
+
+// Like is a LIKE pattern whose wildcards are kept, e.g. Like("Acme%"). Use StartsWith, EndsWith or Contains to match
+// a value containing wildcards literally.
+type Like string
+
+// StartsWith returns a LIKE pattern matching the values starting with s.
+func StartsWith(s string) Like {
+	return Like(likeEscaper.Replace(s) + "%")
+}
+
+// EndsWith returns a LIKE pattern matching the values ending with s.
+func EndsWith(s string) Like {
+	return Like("%" + likeEscaper.Replace(s))
+}
+
+// Contains returns a LIKE pattern matching the values containing s.
+func Contains(s string) Like {
+	return Like("%" + likeEscaper.Replace(s) + "%")
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern, the escapes of the string literal are added by the builder.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// QueryBuilder builds a SOQL query, escaping the values of its conditions:
+//
+//	q, err := simpleforce.Select("Id", "Name", "Account.Name").
+//		From("Contact").
+//		Where("Account.Name = ?", name).
+//		Where("CreatedDate > ?", since).
+//		OrderBy("Name").
+//		Limit(10).
+//		Build()
+type QueryBuilder struct {
+	fields  []string
+	from    string
+	where   []string
+	orderBy []string
+	limit   int
+	offset  int
+	err     error
+}
+
+// Select starts a query selecting fields, which may be relationship fields such as Account.Name or functions such
+// as FIELDS(ALL) and COUNT().
+func Select(fields ...string) *QueryBuilder {
+	return &QueryBuilder{fields: fields}
+}
+
+// From sets the object queried.
+func (qb *QueryBuilder) From(object string) *QueryBuilder {
+	qb.from = object
+	return qb
+}
+
+// Subquery adds a child relationship subquery to the selected fields, e.g.
+// Select("Id").Subquery(Select("Id", "Name").From("Contacts")).From("Account").
+func (qb *QueryBuilder) Subquery(child *QueryBuilder) *QueryBuilder {
+	q, err := child.Build()
+	if err != nil {
+		qb.setErr(err)
+		return qb
+	}
+	qb.fields = append(qb.fields, "("+q+")")
+	return qb
+}
+
+// Where adds a condition, combined with the previous ones with AND; conditions are parenthesized when there are more
+// than one. Each ? in cond is replaced by the literal of the next arg: strings are quoted and escaped, nil is null,
+// time.Time is a datetime, Date a date, Like a LIKE pattern, and slices are lists for IN and NOT IN. NaN and infinite
+// numbers and byte slices have no literal. Literals such as TODAY or LAST_N_DAYS:30 can be written in cond directly.
+func (qb *QueryBuilder) Where(cond string, args ...interface{}) *QueryBuilder {
+	bound, err := bindArgs(cond, args)
+	if err != nil {
+		qb.setErr(err)
+		return qb
+	}
+	qb.where = append(qb.where, bound)
+	return qb
+}
+
+// WhereIn adds a condition matching the records whose field is one of values, which must be a slice.
+func (qb *QueryBuilder) WhereIn(field string, values interface{}) *QueryBuilder {
+	return qb.Where(field+" IN ?", values)
+}
+
+// OrderBy sets the order of the records, e.g. OrderBy("Name DESC NULLS LAST", "Id").
+func (qb *QueryBuilder) OrderBy(fields ...string) *QueryBuilder {
+	qb.orderBy = append(qb.orderBy, fields...)
+	return qb
+}
+
+// Limit sets the maximum number of records returned.
+func (qb *QueryBuilder) Limit(n int) *QueryBuilder {
+	qb.limit = n
+	return qb
+}
+
+// Offset sets the number of records skipped.
+func (qb *QueryBuilder) Offset(n int) *QueryBuilder {
+	qb.offset = n
+	return qb
+}
+
+// Build returns the query, or the first error found while building it, e.g. a missing argument or a value which has
+// no SOQL literal.
+func (qb *QueryBuilder) Build() (string, error) {
+	if qb.err != nil {
+		return "", qb.err
+	}
+	if len(qb.fields) == 0 || qb.from == "" {
+		return "", fmt.Errorf("query requires fields and an object")
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	b.WriteString(strings.Join(qb.fields, ", "))
+	b.WriteString(" FROM ")
+	b.WriteString(qb.from)
+	for i, cond := range qb.where {
+		if i == 0 {
+			b.WriteString(" WHERE ")
+		} else {
+			b.WriteString(" AND ")
+		}
+		if len(qb.where) > 1 {
+			// An OR in a condition must not bind with the AND of the others.
+			b.WriteString("(" + cond + ")")
+		} else {
+			b.WriteString(cond)
+		}
+	}
+	if len(qb.orderBy) > 0 {
+		b.WriteString(" ORDER BY ")
+		b.WriteString(strings.Join(qb.orderBy, ", "))
+	}
+	if qb.limit > 0 {
+		b.WriteString(" LIMIT ")
+		b.WriteString(strconv.Itoa(qb.limit))
+	}
+	if qb.offset > 0 {
+		b.WriteString(" OFFSET ")
+		b.WriteString(strconv.Itoa(qb.offset))
+	}
+	return b.String(), nil
+}
+
+// String returns the query, or an empty string if it is invalid, see Build.
+func (qb *QueryBuilder) String() string {
+	q, _ := qb.Build()
+	return q
+}
+
+func (qb *QueryBuilder) setErr(err error) {
+	if qb.err == nil {
+		qb.err = err
+	}
+}
+
+// bindArgs replaces the ? placeholders outside of string literals in cond by the literals of args.
+func bindArgs(cond string, args []interface{}) (string, error) {
+	var b strings.Builder
+	next := 0
+	quoted, escaped := false, false
+	for _, r := range cond {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '\'':
+			quoted = !quoted
+		case !quoted && r == '?':
+			if next >= len(args) {
+				return "", fmt.Errorf("missing argument %d for %q", next+1, cond)
+			}
+			literal, err := soqlLiteral(args[next])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(literal)
+			next++
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if next != len(args) {
+		return "", fmt.Errorf("%d arguments for %d placeholders in %q", len(args), next, cond)
+	}
+	return b.String(), nil
+}
+
+// likeLiteral quotes a LIKE pattern, keeping the backslashes escaping wildcards and backslashes as they are.
+func likeLiteral(pattern string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune(`\%_`, runes[i+1]) {
+			b.WriteRune('\\')
+			b.WriteRune(runes[i+1])
+			i++
+			continue
+		}
+		b.WriteString(EscapeString(string(runes[i])))
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// soqlLiteral formats a value as a SOQL literal.
+func soqlLiteral(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return "'" + EscapeString(v) + "'", nil
+	case Like:
+		return likeLiteral(string(v)), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case time.Time:
+		return v.UTC().Format("2006-01-02T15:04:05Z"), nil
+	case *time.Time:
+		if v == nil {
+			return "null", nil
+		}
+		return v.UTC().Format("2006-01-02T15:04:05Z"), nil
+	case Date:
+		return time.Time(v).Format("2006-01-02"), nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("unsupported value %v of type %T", value, value)
+		}
+		bitSize := 64
+		if rv.Kind() == reflect.Float32 {
+			bitSize = 32
+		}
+		return strconv.FormatFloat(f, 'f', -1, bitSize), nil
+	case reflect.String:
+		return soqlLiteral(rv.String())
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			// Bytes could be meant as text or as a list of numbers.
+			return "", fmt.Errorf("unsupported value %v of type %T, convert it to a string", value, value)
+		}
+		if rv.Len() == 0 {
+			return "", fmt.Errorf("empty list")
+		}
+		literals := make([]string, rv.Len())
+		for i := range literals {
+			literal, err := soqlLiteral(rv.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			literals[i] = literal
+		}
+		return "(" + strings.Join(literals, ", ") + ")", nil
+	}
+	return "", fmt.Errorf("unsupported value %v of type %T", value, value)
+}
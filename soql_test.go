@@ -0,0 +1,116 @@
+package simpleforce
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEscapeString(t *testing.T) {
+	tests := map[string]string{
+		"O'Brien":          `O\'Brien`,
+		`C:\temp`:          `C:\\temp`,
+		"say \"hi\"":       `say \"hi\"`,
+		"line\nbreak\ttab": `line\nbreak\ttab`,
+		"' OR Name != '":   `\' OR Name != \'`,
+	}
+	for in, want := range tests {
+		if got := EscapeString(in); got != want {
+			t.Errorf("EscapeString(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := EscapeLike("50%_off's"); got != `50\%\_off\'s` {
+		t.Errorf("unexpected %q", got)
+	}
+}
+
+func TestQueryBuilder(t *testing.T) {
+	since := time.Date(2024, 1, 31, 12, 30, 0, 0, time.FixedZone("EET", 2*60*60))
+	tests := []struct {
+		qb   *QueryBuilder
+		want string
+	}{
+		{
+			Select("FIELDS(ALL)").From("ScratchOrgInfo").Where("OrgName = ?", "x").Where("Status = 'Active'").Limit(2),
+			"SELECT FIELDS(ALL) FROM ScratchOrgInfo WHERE (OrgName = 'x') AND (Status = 'Active') LIMIT 2",
+		},
+		{
+			Select("Id", "Account.Name").From("Contact").
+				Where("LastName = ? AND CreatedDate > ?", "O'Brien", since).
+				Where("Birthdate = ?", Date(time.Date(1990, 5, 1, 0, 0, 0, 0, time.UTC))).
+				OrderBy("LastName DESC", "Id").
+				Limit(10).
+				Offset(20),
+			`SELECT Id, Account.Name FROM Contact WHERE (LastName = 'O\'Brien' AND CreatedDate > 2024-01-31T10:30:00Z) ` +
+				`AND (Birthdate = 1990-05-01) ORDER BY LastName DESC, Id LIMIT 10 OFFSET 20`,
+		},
+		{
+			Select("Id").From("Account").Where("Name = ? OR Name = ?", "a", "b").Where("IsDeleted = ?", false),
+			"SELECT Id FROM Account WHERE (Name = 'a' OR Name = 'b') AND (IsDeleted = false)",
+		},
+		{
+			Select("Id").From("Account").Where("Name = ?", "a OR b").Where("Site = 'x or y'"),
+			"SELECT Id FROM Account WHERE (Name = 'a OR b') AND (Site = 'x or y')",
+		},
+		{
+			Select("Id").From("Account").Where("Name = ? OR ParentId = ?", "a", nil),
+			"SELECT Id FROM Account WHERE Name = 'a' OR ParentId = null",
+		},
+		{
+			Select("Id").From("Account").Where("AnnualRevenue > ?", float32(0.1)).Where("NumberOfEmployees < ?", 1e21),
+			"SELECT Id FROM Account WHERE (AnnualRevenue > 0.1) AND (NumberOfEmployees < 1000000000000000000000)",
+		},
+		{
+			Select("Id").From("Account").Where("(Name = ?)OR(Name = ?)", "a", "b").Where("Site = ?\tOR\nSite = null", "x"),
+			"SELECT Id FROM Account WHERE ((Name = 'a')OR(Name = 'b')) AND (Site = 'x'\tOR\nSite = null)",
+		},
+		{
+			Select("Id").From("Account").WhereIn("Id", []string{"001A", "001'B"}).Where("NumberOfEmployees IN ?", []int{1, 2}),
+			`SELECT Id FROM Account WHERE (Id IN ('001A', '001\'B')) AND (NumberOfEmployees IN (1, 2))`,
+		},
+		{
+			Select("Id").From("Account").Where("Name LIKE ?", StartsWith("50%_o'k")).Where("Site LIKE ?", Like("Acme%")),
+			`SELECT Id FROM Account WHERE (Name LIKE '50\%\_o\'k%') AND (Site LIKE 'Acme%')`,
+		},
+		{
+			Select("Id").From("Account").Where("Name LIKE ?", Contains(`a\b`)).Where("Site LIKE ?", EndsWith("x")),
+			`SELECT Id FROM Account WHERE (Name LIKE '%a\\b%') AND (Site LIKE '%x')`,
+		},
+		{
+			Select("Id").Subquery(Select("Id", "Name").From("Contacts").Where("Email = ?", "a@example.com")).From("Account").
+				Where("Name != 'what?' AND AnnualRevenue > ?", 1.5),
+			"SELECT Id, (SELECT Id, Name FROM Contacts WHERE Email = 'a@example.com') FROM Account " +
+				"WHERE Name != 'what?' AND AnnualRevenue > 1.5",
+		},
+	}
+	for _, test := range tests {
+		got, err := test.qb.Build()
+		if err != nil || got != test.want {
+			t.Errorf("unexpected query %q %v, want %q", got, err, test.want)
+		}
+		if test.qb.String() != got {
+			t.Errorf("unexpected string %q", test.qb.String())
+		}
+	}
+
+	// Negative: placeholders without arguments, unused arguments, empty lists, unsupported values and incomplete
+	// queries are rejected.
+	invalid := []*QueryBuilder{
+		Select("Id").From("Account").Where("Name = ?"),
+		Select("Id").From("Account").Where("Name = 'x'", "x"),
+		Select("Id").From("Account").WhereIn("Id", []string{}),
+		Select("Id").From("Account").Where("Name = ?", struct{}{}),
+		Select("Id").From("Account").Where("AnnualRevenue > ?", math.NaN()),
+		Select("Id").From("Account").Where("AnnualRevenue < ?", math.Inf(1)),
+		Select("Id").From("Account").WhereIn("AnnualRevenue", []float64{1, math.Inf(-1)}),
+		Select("Id").From("Account").Where("Name = ?", []byte("Acme")),
+		Select("Id").Subquery(Select("Id").From("Contacts").Where("Name = ?")).From("Account"),
+		Select("Id"),
+		Select().From("Account"),
+	}
+	for _, qb := range invalid {
+		if q, err := qb.Build(); err == nil || qb.String() != "" {
+			t.Errorf("unexpected query %q", q)
+		}
+	}
+}
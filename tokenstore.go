@@ -0,0 +1,233 @@
+package simpleforce
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Token is the session state of a Client as persisted by a TokenStore.
+type Token struct {
+	AccessToken  string    `json:"accessToken"`
+	RefreshToken string    `json:"refreshToken,omitempty"`
+	InstanceURL  string    `json:"instanceUrl"`
+	IssuedAt     time.Time `json:"issuedAt"`
+
+	// SharedRefreshToken is set if RefreshToken belongs to another tool such as the Salesforce CLI, so that it is not
+	// revoked by Logout.
+	SharedRefreshToken bool `json:"sharedRefreshToken,omitempty"`
+
+	// LoginURL and ClientID identify where RefreshToken was issued, they are needed to refresh the session.
+	LoginURL string `json:"loginUrl,omitempty"`
+	ClientID string `json:"clientId,omitempty"`
+
+	IdentityURL  string `json:"identityUrl,omitempty"`
+	UserID       string `json:"userId,omitempty"`
+	Username     string `json:"username,omitempty"`
+	UserEmail    string `json:"userEmail,omitempty"`
+	UserFullName string `json:"userFullName,omitempty"`
+}
+
+// TokenStore persists the session of a Client so that it can be shared between processes and reused across restarts.
+// Load returns a nil Token without error if nothing has been stored yet.
+type TokenStore interface {
+	Load() (*Token, error)
+	Save(token *Token) error
+	Clear() error
+}
+
+// SetTokenStore associates store with the client. A session found in the store is loaded into the client right away;
+// afterwards the store is updated after every login and session refresh.
+func (client *Client) SetTokenStore(store TokenStore) error {
+	client.tokenStore = store
+	if store == nil {
+		return nil
+	}
+
+	token, err := store.Load()
+	if err != nil {
+		return err
+	}
+	if token != nil && token.AccessToken != "" {
+		client.restoreToken(token)
+	}
+	return nil
+}
+
+// currentToken captures the session state of the client.
+func (client *Client) currentToken() *Token {
+	s := client.currentSession()
+	return &Token{
+		AccessToken:        s.id,
+		RefreshToken:       s.refreshToken,
+		InstanceURL:        s.instanceURL,
+		SharedRefreshToken: s.sharedToken,
+		IssuedAt:           s.issuedAt,
+		LoginURL:           s.oauthURL,
+		ClientID:           s.oauthClientID,
+		IdentityURL:        s.identityURL,
+		UserID:             s.user.id,
+		Username:           s.user.name,
+		UserEmail:          s.user.email,
+		UserFullName:       s.user.fullName,
+	}
+}
+
+// restoreToken loads a stored session into the client.
+func (client *Client) restoreToken(token *Token) {
+	s := session{
+		id:            token.AccessToken,
+		refreshToken:  token.RefreshToken,
+		sharedToken:   token.SharedRefreshToken,
+		instanceURL:   token.InstanceURL,
+		issuedAt:      token.IssuedAt,
+		oauthURL:      token.LoginURL,
+		oauthClientID: token.ClientID,
+		identityURL:   token.IdentityURL,
+	}
+	s.user.id = token.UserID
+	s.user.name = token.Username
+	s.user.email = token.UserEmail
+	s.user.fullName = token.UserFullName
+	client.setSession(s)
+}
+
+// saveToken writes the session state of the client to the token store, if any.
+func (client *Client) saveToken() error {
+	if client.tokenStore == nil {
+		return nil
+	}
+	err := client.tokenStore.Save(client.currentToken())
+	if err != nil {
+		client.log(LevelError, "failed to save token", "error", err)
+	}
+	return err
+}
+
+// parseIssuedAt converts the issued_at field of a token response (milliseconds since epoch) into time.
+func parseIssuedAt(issuedAt string) time.Time {
+	millis, err := strconv.ParseInt(issuedAt, 10, 64)
+	if err != nil {
+		return time.Now()
+	}
+	return time.Unix(0, millis*int64(time.Millisecond))
+}
+
+// MemoryTokenStore keeps the token in memory. It allows clients in the same process to share one session.
+type MemoryTokenStore struct {
+	mu    sync.Mutex
+	token *Token
+}
+
+// NewMemoryTokenStore creates an empty in-memory token store.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{}
+}
+
+// Load returns a copy of the stored token.
+func (store *MemoryTokenStore) Load() (*Token, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.token == nil {
+		return nil, nil
+	}
+	token := *store.token
+	return &token, nil
+}
+
+// Save stores a copy of token.
+func (store *MemoryTokenStore) Save(token *Token) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if token == nil {
+		store.token = nil
+		return nil
+	}
+	saved := *token
+	store.token = &saved
+	return nil
+}
+
+// Clear removes the stored token.
+func (store *MemoryTokenStore) Clear() error {
+	return store.Save(nil)
+}
+
+// FileTokenStore keeps the token as a JSON file. The file is only readable by the owner as it contains credentials.
+type FileTokenStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileTokenStore creates a token store backed by the file at path. The file is created on the first save.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+// Load reads the token from the file. A missing file is not an error.
+func (store *FileTokenStore) Load() (*Token, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	data, err := ioutil.ReadFile(store.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var token Token
+	err = json.Unmarshal(data, &token)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Save writes the token to the file. The file is replaced atomically so that concurrent readers never see a partial
+// token.
+func (store *FileTokenStore) Save(token *Token) error {
+	if token == nil {
+		return store.Clear()
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(store.path), filepath.Base(store.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), store.path)
+}
+
+// Clear deletes the file.
+func (store *FileTokenStore) Clear() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	err := os.Remove(store.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package simpleforce
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemoryTokenStore(t *testing.T) {
+	store := NewMemoryTokenStore()
+	token, err := store.Load()
+	if err != nil || token != nil {
+		t.Fail()
+	}
+
+	saved := &Token{AccessToken: "__SESSION__", InstanceURL: "https://example.my.salesforce.com"}
+	if store.Save(saved) != nil {
+		t.Fail()
+	}
+	saved.AccessToken = "__CHANGED__"
+	token, err = store.Load()
+	if err != nil || token == nil || token.AccessToken != "__SESSION__" {
+		t.Fail()
+	}
+
+	if store.Clear() != nil {
+		t.Fail()
+	}
+	token, err = store.Load()
+	if err != nil || token != nil {
+		t.Fail()
+	}
+}
+
+func TestFileTokenStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	store := NewFileTokenStore(path)
+	token, err := store.Load()
+	if err != nil || token != nil {
+		t.Fail()
+	}
+
+	issuedAt := time.Unix(1636385425, 0).UTC()
+	err = store.Save(&Token{
+		AccessToken:  "__SESSION__",
+		RefreshToken: "__REFRESH__",
+		InstanceURL:  "https://example.my.salesforce.com",
+		IssuedAt:     issuedAt,
+		UserID:       "005000000000001",
+		Username:     "user@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fail()
+	}
+
+	token, err = NewFileTokenStore(path).Load()
+	if err != nil || token == nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "__SESSION__" || token.RefreshToken != "__REFRESH__" || !token.IssuedAt.Equal(issuedAt) ||
+		token.Username != "user@example.com" {
+		t.Fail()
+	}
+
+	if store.Clear() != nil || store.Clear() != nil {
+		t.Fail()
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Fail()
+	}
+}
+
+func TestClient_SetTokenStore(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"access_token":"__SESSION__","refresh_token":"__REFRESH__","instance_url":"%s","id":"%s/id/00D000000000001/005000000000001","issued_at":"1636385425594"}`,
+			server.URL, server.URL)
+	}))
+	defer server.Close()
+
+	// Login writes the session to the store.
+	store := NewMemoryTokenStore()
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	if client.SetTokenStore(store) != nil || client.isLoggedIn() {
+		t.Fail()
+	}
+	err := client.LoginWithAuthCode(server.URL, "__CODE__")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ := store.Load()
+	if token == nil || token.AccessToken != "__SESSION__" || token.RefreshToken != "__REFRESH__" ||
+		token.InstanceURL != server.URL || token.LoginURL != server.URL || token.ClientID != DefaultClientID ||
+		token.UserID != "005000000000001" || token.IssuedAt.UnixNano() != 1636385425594*int64(time.Millisecond) {
+		t.Fatalf("unexpected token %+v", token)
+	}
+
+	// Another client picks up the stored session.
+	other := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	if other.SetTokenStore(store) != nil {
+		t.Fail()
+	}
+	if other.GetSid() != "__SESSION__" || other.GetLoc() != server.URL || other.currentSession().refreshToken != "__REFRESH__" ||
+		other.currentSession().oauthURL != server.URL || other.currentSession().user.id != "005000000000001" {
+		t.Fail()
+	}
+}
@@ -1,10 +1,10 @@
 package simpleforce
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"net/url"
 )
 
@@ -20,29 +20,41 @@ type ExecuteAnonymousResult struct {
 }
 
 // Tooling is called to specify Tooling API, e.g. client.Tooling().Query(q)
+// It returns a view of the client sending its requests to the Tooling API. The view shares the session and
+// configuration of the client, which itself keeps using the regular API.
 func (client *Client) Tooling() *Client {
-	client.useToolingAPI = true
-	return client
+	return &Client{clientState: client.clientState, useToolingAPI: true}
 }
 
-func (client *Client) UnTooling() {
-	client.useToolingAPI = false
+// UnTooling returns a view of the client sending its requests to the regular API, e.g. to leave a view returned by
+// Tooling. The view shares the session and configuration of the client.
+//
+// Deprecated: Tooling no longer changes the client it is called on, use the client itself instead of the view
+// returned by Tooling.
+func (client *Client) UnTooling() *Client {
+	return &Client{clientState: client.clientState}
 }
 
 // ExecuteAnonymous executes a body of Apex code
 func (client *Client) ExecuteAnonymous(apexBody string) (*ExecuteAnonymousResult, error) {
+	return client.ExecuteAnonymousContext(context.Background(), apexBody)
+}
+
+// ExecuteAnonymousContext is like ExecuteAnonymous, the request is bound to ctx.
+func (client *Client) ExecuteAnonymousContext(ctx context.Context, apexBody string) (*ExecuteAnonymousResult, error) {
 	if !client.isLoggedIn() {
 		return nil, ErrAuthentication
 	}
 
 	// Create the endpoint
 	formatString := "%s/services/data/v%s/tooling/executeAnonymous/?anonymousBody=%s"
-	baseURL := client.instanceURL
-	endpoint := fmt.Sprintf(formatString, baseURL, client.apiVersion, url.QueryEscape(apexBody))
+	baseURL := client.currentSession().instanceURL
+	endpoint := fmt.Sprintf(formatString, baseURL, client.version(), url.QueryEscape(apexBody))
 
-	data, err := client.httpRequest("GET", endpoint, nil)
+	// The Apex may have run although the request failed, it must not be retried.
+	data, err := client.httpRequest(withNonIdempotent(ctx), "GET", endpoint, nil)
 	if err != nil {
-		log.Println(logPrefix, "HTTP GET request failed:", endpoint)
+		client.log(LevelError, "execute anonymous failed", "error", err)
 		return nil, err
 	}
 
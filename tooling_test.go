@@ -32,3 +32,12 @@ func TestClient_ExecuteAnonymous(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestClient_UnTooling(t *testing.T) {
+	client := NewClient("https://example.my.salesforce.com", DefaultClientID, DefaultAPIVersion)
+	tooling := client.Tooling()
+	regular := tooling.UnTooling()
+	if client.useToolingAPI || !tooling.useToolingAPI || regular.useToolingAPI || regular.clientState != client.clientState {
+		t.Fail()
+	}
+}
@@ -0,0 +1,121 @@
+package simpleforce
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// APIVersion describes a version of the REST API available on an org.
+type APIVersion struct {
+	Label   string `json:"label"`
+	URL     string `json:"url"`
+	Version string `json:"version"`
+}
+
+// Versions lists the API versions available on the instance of the session, or on the login URL of the client if it
+// is not logged in yet.
+// Ref: https://developer.salesforce.com/docs/atlas.en-us.api_rest.meta/api_rest/resources_versions.htm
+func (client *Client) Versions() ([]APIVersion, error) {
+	return client.VersionsContext(context.Background())
+}
+
+// VersionsContext is like Versions, the request is bound to ctx.
+func (client *Client) VersionsContext(ctx context.Context) ([]APIVersion, error) {
+	baseURL := client.currentSession().instanceURL
+	if baseURL == "" {
+		baseURL = client.baseURL
+	}
+
+	data, err := client.httpRequest(ctx, http.MethodGet, baseURL+"/services/data", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []APIVersion
+	err = json.Unmarshal(data, &versions)
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// NegotiateAPIVersion keeps the API version of the client if the org supports it, and pins the client to the latest
+// version supported by the org otherwise, e.g. if the client was created without a version. The version used is
+// returned. Like the Set... methods, it must be called before the client is shared.
+func (client *Client) NegotiateAPIVersion() (string, error) {
+	return client.NegotiateAPIVersionContext(context.Background())
+}
+
+// NegotiateAPIVersionContext is like NegotiateAPIVersion, the request is bound to ctx.
+func (client *Client) NegotiateAPIVersionContext(ctx context.Context) (string, error) {
+	versions, err := client.VersionsContext(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	latest := ""
+	for _, version := range versions {
+		if version.Version == client.apiVersion {
+			return client.apiVersion, nil
+		}
+		if latest == "" || compareVersions(version.Version, latest) > 0 {
+			latest = version.Version
+		}
+	}
+	if latest == "" {
+		return "", ErrFailure
+	}
+	client.apiVersion = latest
+	return latest, nil
+}
+
+// version returns the API version used by the client, DefaultAPIVersion if none was configured or negotiated yet.
+func (client *Client) version() string {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+	if client.apiVersion == "" {
+		return DefaultAPIVersion
+	}
+	return client.apiVersion
+}
+
+// ensureAPIVersion negotiates the API version after a login if the client was created without one. The default
+// version is kept if the versions cannot be listed.
+func (client *Client) ensureAPIVersion(ctx context.Context) {
+	client.mu.RLock()
+	configured := client.apiVersion != ""
+	client.mu.RUnlock()
+	if configured {
+		return
+	}
+	if _, err := client.NegotiateAPIVersionContext(ctx); err != nil {
+		client.log(LevelWarn, "api version negotiation failed, using default", "version", DefaultAPIVersion, "error", err)
+	}
+}
+
+// compareVersions compares two API versions such as "62.0" numerically.
+func compareVersions(a, b string) int {
+	partsA := strings.Split(a, ".")
+	partsB := strings.Split(b, ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var x, y int
+		if i < len(partsA) {
+			x, _ = strconv.Atoi(partsA[i])
+		}
+		if i < len(partsB) {
+			y, _ = strconv.Atoi(partsB[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,97 @@
+package simpleforce
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/veloceapps/simpleforce/simpleforcetest"
+)
+
+func TestClient_NegotiateAPIVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/services/data":
+			if r.Header.Get("Authorization") != "" && r.Header.Get("Authorization") != "Bearer __SESSION__" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `[{"label":"Winter '24","url":"/services/data/v59.0","version":"59.0"},{"label":"Summer '24","url":"/services/data/v61.0","version":"61.0"},{"label":"Spring '24","url":"/services/data/v60.0","version":"60.0"}]`)
+		case strings.HasPrefix(r.URL.Path, "/services/data/v61.0/query"):
+			fmt.Fprint(w, `{"totalSize":0,"done":true,"records":[]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// The versions are listed without a session.
+	client := NewClient(server.URL, DefaultClientID, "v60.0")
+	versions, err := client.Versions()
+	if err != nil || len(versions) != 3 || versions[0].Version != "59.0" || versions[0].URL != "/services/data/v59.0" {
+		t.Fatalf("unexpected versions %+v %v", versions, err)
+	}
+
+	// A supported version is kept.
+	if version, err := client.NegotiateAPIVersion(); err != nil || version != "60.0" || client.version() != "60.0" {
+		t.Errorf("unexpected version %q %v", version, err)
+	}
+
+	// The latest version is used if none or an unsupported one is configured.
+	for _, configured := range []string{"", "40.0"} {
+		client = NewClient(server.URL, DefaultClientID, configured)
+		if configured == "" && client.version() != DefaultAPIVersion {
+			t.Fail()
+		}
+		client.SetSidLoc("__SESSION__", server.URL)
+		if version, err := client.NegotiateAPIVersion(); err != nil || version != "61.0" {
+			t.Errorf("%q: unexpected version %q %v", configured, version, err)
+		}
+		if _, err = client.Query("SELECT Id FROM Case"); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// Negative: the versions cannot be listed.
+	client = NewClient(server.URL+"/missing", DefaultClientID, "")
+	if _, err = client.NegotiateAPIVersion(); err == nil || client.version() != DefaultAPIVersion {
+		t.Errorf("unexpected %v", err)
+	}
+}
+
+func TestClient_LoginNegotiateAPIVersion(t *testing.T) {
+	server := simpleforcetest.NewServer()
+	defer server.Close()
+
+	// The login response does not keep its slot while the version is negotiated.
+	client := NewClient(server.URL, DefaultClientID, "")
+	client.SetMaxConcurrentRequests(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.LoginPasswordContext(ctx, simpleforcetest.Username, simpleforcetest.Password, ""); err != nil {
+		t.Fatal(err)
+	}
+	client.mu.RLock()
+	negotiated := client.apiVersion
+	client.mu.RUnlock()
+	if negotiated != "62.0" {
+		t.Errorf("unexpected version %q", negotiated)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	if compareVersions("9.0", "10.0") >= 0 || compareVersions("62.0", "61.0") <= 0 || compareVersions("61.0", "61") != 0 {
+		t.Fail()
+	}
+}
+
+func TestScratchPackageXML(t *testing.T) {
+	xml := scratchPackageXML("58.0")
+	if !strings.Contains(xml, "<version>58.0</version>") || strings.Contains(xml, "53.0") {
+		t.Fail()
+	}
+}
@@ -0,0 +1,244 @@
+package simpleforce
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WebLoginOptions configures the interactive login of LoginWeb.
+type WebLoginOptions struct {
+	// RedirectURI is the callback URL of the connected app, it must be a loopback http address such as
+	// http://localhost:1717/OauthRedirect as it is served by LoginWeb. The port defaults to 80. DefaultRedirectURI is
+	// used if empty.
+	RedirectURI string
+
+	// Scope lists the requested OAuth scopes separated by spaces, e.g. "api refresh_token". The scopes configured for
+	// the connected app are used if empty.
+	Scope string
+
+	// OpenBrowser is called with the authorization URL the user has to visit. The system browser is opened if nil.
+	OpenBrowser func(authorizeURL string) error
+}
+
+// PKCE holds a proof key for code exchange used by the web server flow.
+// Ref: https://help.salesforce.com/s/articleView?id=sf.remoteaccess_pkce.htm
+type PKCE struct {
+	Verifier  string
+	Challenge string
+}
+
+// NewPKCE generates a random code verifier and its S256 code challenge.
+func NewPKCE() (*PKCE, error) {
+	verifier, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(verifier))
+	return &PKCE{
+		Verifier:  verifier,
+		Challenge: base64.RawURLEncoding.EncodeToString(digest[:]),
+	}, nil
+}
+
+// AuthorizeURL builds the URL of the authorization page of the web server flow for the client ID of the client.
+// state and codeChallenge are optional.
+func (client *Client) AuthorizeURL(redirectURI, scope, state, codeChallenge string) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", client.clientID)
+	params.Set("redirect_uri", redirectURI)
+	if scope != "" {
+		params.Set("scope", scope)
+	}
+	if state != "" {
+		params.Set("state", state)
+	}
+	if codeChallenge != "" {
+		params.Set("code_challenge", codeChallenge)
+		params.Set("code_challenge_method", "S256")
+	}
+	return fmt.Sprintf("%s/services/oauth2/authorize?%s", client.baseURL, params.Encode())
+}
+
+// LoginWithAuthCodePKCE is like LoginWithAuthCode, but uses the client ID of the client and sends the code verifier
+// matching the code challenge of the authorization request.
+func (client *Client) LoginWithAuthCodePKCE(loginURL, redirectURI, code, codeVerifier string) error {
+	return client.LoginWithAuthCodePKCEContext(context.Background(), loginURL, redirectURI, code, codeVerifier)
+}
+
+// LoginWithAuthCodePKCEContext is like LoginWithAuthCodePKCE, the token request is bound to ctx.
+func (client *Client) LoginWithAuthCodePKCEContext(ctx context.Context, loginURL, redirectURI, code, codeVerifier string) error {
+	return client.exchangeAuthCode(ctx, loginURL, client.clientID, redirectURI, code, codeVerifier)
+}
+
+// LoginWeb signs into salesforce interactively with the OAuth 2.0 web server flow. The user is sent to the
+// authorization page of the login URL of the client, while a local listener on the redirect URI waits for the
+// authorization code. The state is verified and the code is exchanged with PKCE. LoginWeb returns when the flow
+// completes or ctx is done.
+// Ref: https://help.salesforce.com/s/articleView?id=sf.remoteaccess_oauth_web_server_flow.htm
+func (client *Client) LoginWeb(ctx context.Context, opts WebLoginOptions) error {
+	redirectURI := opts.RedirectURI
+	if redirectURI == "" {
+		redirectURI = DefaultRedirectURI
+	}
+	redirect, err := url.Parse(redirectURI)
+	if err != nil {
+		return err
+	}
+	addr, err := loopbackAddress(redirect)
+	if err != nil {
+		return err
+	}
+	openBrowser := opts.OpenBrowser
+	if openBrowser == nil {
+		openBrowser = openSystemBrowser
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return err
+	}
+	pkce, err := NewPKCE()
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	type callbackResult struct {
+		code string
+		err  error
+	}
+	results := make(chan callbackResult, 1)
+	callbackPath := redirect.Path
+	if callbackPath == "" {
+		callbackPath = "/"
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(callbackPath, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != callbackPath || (query.Get("code") == "" && query.Get("state") == "" && query.Get("error") == "") {
+			// Not the callback, e.g. the browser asking for /favicon.ico, keep waiting.
+			http.NotFound(w, r)
+			return
+		}
+		var result callbackResult
+		switch {
+		case query.Get("error") != "":
+			result.err = fmt.Errorf("authorization failed: %s %s", query.Get("error"),
+				query.Get("error_description"))
+		case query.Get("state") != state:
+			result.err = errors.New("authorization state mismatch")
+		case query.Get("code") == "":
+			result.err = errors.New("authorization code missing")
+		default:
+			result.code = query.Get("code")
+		}
+
+		if result.err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "<html><body>Login failed: %s</body></html>", html.EscapeString(result.err.Error()))
+		} else {
+			fmt.Fprint(w, "<html><body>Login succeeded, you can close this window.</body></html>")
+		}
+		select {
+		case results <- result:
+		default:
+		}
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+
+	authorizeURL := client.AuthorizeURL(redirectURI, opts.Scope, state, pkce.Challenge)
+	client.log(LevelInfo, "opening browser for login", "url", strings.SplitN(authorizeURL, "?", 2)[0])
+	err = openBrowser(authorizeURL)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case result := <-results:
+		if result.err != nil {
+			return result.err
+		}
+		return client.LoginWithAuthCodePKCEContext(ctx, client.baseURL, redirectURI, result.code, pkce.Verifier)
+	}
+}
+
+// loopbackAddress returns the address to listen on for the redirect URI of the web server flow, which must be a
+// loopback http address. The port defaults to 80.
+func loopbackAddress(redirect *url.URL) (string, error) {
+	host := redirect.Hostname()
+	if redirect.Scheme != "http" || host == "" {
+		return "", errors.New("redirect uri must be a local http address: " + redirect.String())
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return "", errors.New("redirect uri must be a loopback address: " + redirect.String())
+	}
+	port := redirect.Port()
+	if port == "" {
+		port = "80"
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// exchangeAuthCode redeems an authorization code of the web server flow.
+func (client *Client) exchangeAuthCode(ctx context.Context, loginURL, clientID, redirectURI, code, codeVerifier string) error {
+	data := url.Values{}
+	data.Set("grant_type", "authorization_code")
+	data.Set("code", code)
+	data.Set("client_id", clientID)
+	data.Set("redirect_uri", redirectURI)
+	if codeVerifier != "" {
+		data.Set("code_verifier", codeVerifier)
+	}
+	client.addClientSecret(data, clientID)
+
+	token, err := client.requestToken(ctx, loginURL, data)
+	if err != nil {
+		return err
+	}
+	return client.applyToken(ctx, token, loginURL, clientID, "")
+}
+
+// randomToken returns a random URL safe string, used for state and code verifiers.
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// openSystemBrowser opens u in the default browser of the system.
+func openSystemBrowser(u string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+	return cmd.Start()
+}
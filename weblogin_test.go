@@ -0,0 +1,186 @@
+package simpleforce
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// requireRedirectURI returns a redirect URI on a free local port.
+func requireRedirectURI(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return fmt.Sprintf("http://%s/OauthRedirect", addr)
+}
+
+func TestNewPKCE(t *testing.T) {
+	pkce, err := NewPKCE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(pkce.Verifier))
+	if len(pkce.Verifier) < 43 || pkce.Challenge != base64.RawURLEncoding.EncodeToString(digest[:]) {
+		t.Fail()
+	}
+
+	other, _ := NewPKCE()
+	if other.Verifier == pkce.Verifier {
+		t.Fail()
+	}
+}
+
+func TestClient_LoginWeb(t *testing.T) {
+	challenges := map[string]string{}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		digest := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if r.FormValue("grant_type") != "authorization_code" || r.FormValue("client_id") != DefaultClientID ||
+			challenges[r.FormValue("code")] != base64.RawURLEncoding.EncodeToString(digest[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid_grant","error_description":"invalid code verifier"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"__SESSION__","refresh_token":"__REFRESH__","instance_url":"%s"}`, server.URL)
+	}))
+	defer server.Close()
+
+	redirectURI := requireRedirectURI(t)
+	// authorize plays the role of the user approving access in the browser.
+	authorize := func(tamper func(url.Values)) func(string) error {
+		return func(authorizeURL string) error {
+			u, err := url.Parse(authorizeURL)
+			if err != nil {
+				return err
+			}
+			params := u.Query()
+			if u.Path != "/services/oauth2/authorize" || params.Get("redirect_uri") != redirectURI ||
+				params.Get("code_challenge_method") != "S256" || params.Get("scope") != "api refresh_token" {
+				return fmt.Errorf("unexpected authorize url %s", authorizeURL)
+			}
+			challenges["__CODE__"] = params.Get("code_challenge")
+
+			callback := url.Values{}
+			callback.Set("code", "__CODE__")
+			callback.Set("state", params.Get("state"))
+			tamper(callback)
+			go func() {
+				// Requests which are not the callback are ignored.
+				base, _ := url.Parse(redirectURI)
+				favicon := base.ResolveReference(&url.URL{Path: "/favicon.ico"}).String()
+				for _, other := range []string{favicon, redirectURI} {
+					if resp, err := http.Get(other); err == nil {
+						resp.Body.Close()
+						if resp.StatusCode != http.StatusNotFound {
+							t.Errorf("unexpected status %d for %s", resp.StatusCode, other)
+						}
+					}
+				}
+				http.Get(redirectURI + "?" + callback.Encode())
+			}()
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	err := client.LoginWeb(ctx, WebLoginOptions{
+		RedirectURI: redirectURI,
+		Scope:       "api refresh_token",
+		OpenBrowser: authorize(func(url.Values) {}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.GetSid() != "__SESSION__" || client.currentSession().refreshToken != "__REFRESH__" {
+		t.Fail()
+	}
+
+	// The callback may be served at the root, where the browser also asks for /favicon.ico.
+	redirectURI = strings.TrimSuffix(redirectURI, "OauthRedirect")
+	client = NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	err = client.LoginWeb(ctx, WebLoginOptions{
+		RedirectURI: redirectURI,
+		Scope:       "api refresh_token",
+		OpenBrowser: authorize(func(url.Values) {}),
+	})
+	if err != nil || client.GetSid() != "__SESSION__" {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	// Negative: state does not match.
+	client = NewClient(server.URL, DefaultClientID, DefaultAPIVersion)
+	err = client.LoginWeb(ctx, WebLoginOptions{
+		RedirectURI: redirectURI,
+		Scope:       "api refresh_token",
+		OpenBrowser: authorize(func(v url.Values) { v.Set("state", "__FORGED__") }),
+	})
+	if err == nil || client.isLoggedIn() {
+		t.Fail()
+	}
+
+	// Negative: user denied access.
+	err = client.LoginWeb(ctx, WebLoginOptions{
+		RedirectURI: redirectURI,
+		Scope:       "api refresh_token",
+		OpenBrowser: authorize(func(v url.Values) { v.Del("code"); v.Set("error", "access_denied") }),
+	})
+	if err == nil || client.isLoggedIn() {
+		t.Fail()
+	}
+
+	// Negative: the redirect URI is not a loopback address.
+	err = client.LoginWeb(ctx, WebLoginOptions{
+		RedirectURI: "http://example.com:1717/OauthRedirect",
+		OpenBrowser: func(string) error { t.Error("browser opened"); return nil },
+	})
+	if err == nil {
+		t.Fail()
+	}
+
+	// Negative: nobody completes the flow.
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer shortCancel()
+	err = client.LoginWeb(shortCtx, WebLoginOptions{
+		RedirectURI: redirectURI,
+		OpenBrowser: func(string) error { return nil },
+	})
+	if err != context.DeadlineExceeded {
+		t.Fail()
+	}
+}
+
+func TestLoopbackAddress(t *testing.T) {
+	tests := map[string]string{
+		"http://localhost:1717/OauthRedirect": "localhost:1717",
+		"http://127.0.0.1/callback":           "127.0.0.1:80",
+		"http://[::1]:8080/":                  "[::1]:8080",
+	}
+	for redirectURI, want := range tests {
+		redirect, _ := url.Parse(redirectURI)
+		if addr, err := loopbackAddress(redirect); err != nil || addr != want {
+			t.Errorf("unexpected address %q %v for %s", addr, err, redirectURI)
+		}
+	}
+
+	// Negative: remote hosts and other schemes are rejected.
+	for _, redirectURI := range []string{"http://example.com/cb", "http://10.0.0.1:1717/cb", "https://localhost:1717/cb", "/cb"} {
+		redirect, _ := url.Parse(redirectURI)
+		if _, err := loopbackAddress(redirect); err == nil {
+			t.Errorf("accepted %s", redirectURI)
+		}
+	}
+}